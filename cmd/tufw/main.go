@@ -5,11 +5,13 @@ import (
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/peltho/tufw/internal/core/service"
+	"github.com/peltho/tufw/internal/core/utils"
 )
 
 const (
@@ -20,21 +22,15 @@ const (
 )
 
 func main() {
-	cmd := exec.Command("id", "-u")
-	output, _ := cmd.Output()
-	i, _ := strconv.Atoi(string(output[:len(output)-1]))
-	if i != 0 {
-		log.Fatal("This program must be run as root! (sudo)")
-	}
-
-	cmd = exec.Command("ufw", "status")
-	_, err := cmd.Output()
-	if err != nil {
-		log.Fatal("Cannot find ufw. Is it installed?")
-	}
-
 	colorFlag := flag.String("color", "cyan", "Color value (red, green, blue)")
+	themeFlag := flag.String("theme", "dark", "Theme name (dark, light, high-contrast) or path to a theme file")
 	logFlag := flag.String("log", "", "Log everything into a tufw.log file")
+	keymapFlag := flag.String("keymap", "default", "Key bindings preset (default, vim)")
+	mouseFlag := flag.Bool("mouse", false, "Enable mouse support")
+	transportFlag := flag.String("transport", "", "Prefix running the commands elsewhere, such as \"ssh host\", \"sudo\", \"nsenter -t PID -n\", \"docker exec container\" or \"lxc exec container\"")
+	configFlag := flag.String("config", "", "Configuration file to read instead of /etc/tufw/config.yaml and ~/.config/tufw/config.yaml")
+	groupsFlag := flag.String("groups", "/etc/tufw/groups.conf", "File storing the address groups")
+	revertFlag := flag.Int("revert", 0, "Revert applied changes after this many seconds unless confirmed (0 disables it)")
 	flag.Parse()
 
 	var color tcell.Color
@@ -51,8 +47,81 @@ func main() {
 		log.Fatalf("Invalid color: %s. Allowed values are red, green, blue.", *colorFlag)
 	}
 
-	if *logFlag != "" {
-		f, err := os.OpenFile(*logFlag, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Flags override the configuration files, only when given
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	files := service.ConfigFiles()
+	if *configFlag != "" {
+		if _, err := os.Stat(*configFlag); err != nil {
+			log.Fatal(err)
+		}
+		files = []string{*configFlag}
+	}
+	config, errs := service.LoadConfig(files...)
+	if set["theme"] {
+		config.Theme = *themeFlag
+	}
+	if set["log"] {
+		config.Log = *logFlag
+	}
+	if set["keymap"] {
+		if _, ok := service.Keymaps[*keymapFlag]; !ok {
+			log.Fatalf("Invalid keymap: %s. Allowed values are default, vim.", *keymapFlag)
+		}
+		config.Keymap = *keymapFlag
+	}
+	if set["mouse"] {
+		config.Mouse = *mouseFlag
+	}
+	if set["revert"] {
+		config.Revert = (time.Duration(*revertFlag) * time.Second).String()
+	}
+	if set["groups"] {
+		config.Groups = *groupsFlag
+	}
+	if set["transport"] {
+		transport, err := utils.ParseTransport(*transportFlag)
+		if err != nil {
+			log.Fatalf("Invalid transport: %v", err)
+		}
+		config.Transport = transport.Prefix
+	}
+	// Flags may not agree with the settings of the files
+	errs = append(errs, config.Validate()...)
+
+	// An invalid theme is listed with the other errors, shown with the default one
+	theme, err := service.LoadTheme(config.Theme)
+	if err != nil {
+		config.Theme = service.DefaultConfig().Theme
+		theme, _ = service.LoadTheme(config.Theme)
+	}
+	// The accent color of the theme is only replaced when asked for
+	if set["color"] {
+		theme = theme.WithAccent(color)
+	}
+
+	if len(errs) > 0 && !service.ConfigErrorScreen(errs, theme) {
+		os.Exit(1)
+	}
+
+	// The checks run where the firewall is, through the transport
+	transport, _ := utils.ParseTransport(config.Transport)
+	utils.SetTransport(transport)
+	output, trace, err := utils.Shellout("id -u")
+	if err != nil && !transport.Local() {
+		// ssh and sudo fail rather than prompt, tell why
+		log.Fatalf("Cannot run commands through %s: %s", transport, strings.TrimSpace(trace))
+	}
+	if i, _ := strconv.Atoi(strings.TrimSpace(output)); i != 0 {
+		log.Fatalf("This program must be run as root on %s! (sudo)", transport)
+	}
+	if _, _, err := utils.Shellout("ufw status"); err != nil {
+		log.Fatalf("Cannot find ufw on %s. Is it installed?", transport)
+	}
+
+	if config.Log != "" {
+		f, err := os.OpenFile(config.Log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -63,6 +132,8 @@ func main() {
 	}
 
 	tui := service.CreateApplication(color)
+	tui.SetTheme(theme)
+	tui.Configure(config)
 	tui.Init()
 	data, err := tui.LoadUFWOutput()
 	if err != nil {
@@ -4,6 +4,8 @@ go 1.23.0
 
 require github.com/gdamore/tcell/v2 v2.9.0
 
+require gopkg.in/yaml.v3 v3.0.1
+
 require (
 	github.com/gdamore/encoding v1.0.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
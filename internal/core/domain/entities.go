@@ -12,10 +12,66 @@ type FormValues struct {
 }
 
 type CellValues struct {
-	Index   string
-	To      string
-	Port    string
-	Action  string
-	From    string
-	Comment string
+	Index     string
+	To        string
+	Port      string
+	Action    string
+	From      string
+	Interface string
+	Comment   string
+}
+
+type Rule struct {
+	Number       int
+	Action       string
+	Direction    string
+	To           string
+	ToPort       string
+	From         string
+	FromPort     string
+	Protocol     string
+	Interface    string
+	InterfaceOut string
+	Comment      string
+	V6           bool
+}
+
+type Finding struct {
+	Kind        string
+	Rule        int
+	Other       int
+	Explanation string
+}
+
+type Packet struct {
+	Direction    string
+	From         string
+	FromPort     string
+	To           string
+	Port         string
+	Protocol     string
+	Interface    string
+	InterfaceOut string
+}
+
+type Listener struct {
+	Protocol  string
+	Address   string
+	Port      string
+	Interface string
+	Process   string
+	V6        bool
+}
+
+type Status struct {
+	Active   bool
+	Logging  string
+	Defaults map[string]string
+}
+
+// RuleDiff pairs a rule of a ruleset with the same rule of another one,
+// either side being nil when the rule is missing there.
+type RuleDiff struct {
+	Left  *Rule
+	Right *Rule
 }
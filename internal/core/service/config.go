@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// Confirmation policies, deciding which changes are applied only once confirmed.
+const (
+	ConfirmAlways      = "always"
+	ConfirmDestructive = "destructive"
+	ConfirmNever       = "never"
+)
+
+const systemConfigFile = "/etc/tufw/config.yaml"
+
+// Config holds the settings read from the configuration files.
+type Config struct {
+	Theme           string          `yaml:"theme"`
+	Log             string          `yaml:"log"`
+	DefaultAction   string          `yaml:"default_action"`
+	Confirm         string          `yaml:"confirm"`
+	RefreshInterval string          `yaml:"refresh_interval"`
+	Revert          string          `yaml:"revert"`
+	Keymap          string          `yaml:"keymap"`
+	Mouse           bool            `yaml:"mouse"`
+	Transport       string          `yaml:"transport"`
+	Groups          string          `yaml:"groups"`
+	Hosts           []Host          `yaml:"hosts"`
+	SSHConfig       bool            `yaml:"ssh_config"`
+	Keys            map[string]Keys `yaml:"keys"`
+	Columns         []string        `yaml:"columns"`
+}
+
+// DefaultConfig returns the settings used when nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		Theme:           "dark",
+		DefaultAction:   actions[0],
+		Confirm:         ConfirmDestructive,
+		RefreshInterval: "0s",
+		Revert:          "0s",
+		Keymap:          "default",
+		Groups:          defaultGroupsFile,
+		Keys:            map[string]Keys{},
+		Columns:         slices.Clone(columns),
+	}
+}
+
+// ConfigFiles returns the configuration files in the order they are read,
+// the user one overriding the system wide one.
+func ConfigFiles() []string {
+	files := []string{systemConfigFile}
+	if dir, err := userConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "tufw", "config.yaml"))
+	}
+	return files
+}
+
+// userConfigDir returns the configuration directory of the user running
+// tufw. Under sudo, it is the one of the user who ran sudo rather than root.
+func userConfigDir() (string, error) {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		if u, err := user.Lookup(name); err == nil {
+			return filepath.Join(u.HomeDir, ".config"), nil
+		}
+	}
+	return os.UserConfigDir()
+}
+
+// ParseConfig reads a configuration file on top of config.
+func ParseConfig(data []byte, config *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// LoadConfig reads the configuration files which exist. Each problem found
+// is reported, along with the file it comes from.
+func LoadConfig(files ...string) (Config, []error) {
+	config := DefaultConfig()
+	var errs []error
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Validate each file on its own so errors point at the right one
+		parsed := DefaultConfig()
+		if err := ParseConfig(data, &parsed); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		if invalid := parsed.Validate(); len(invalid) > 0 {
+			for _, err := range invalid {
+				errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			}
+			continue
+		}
+		ParseConfig(data, &config)
+	}
+
+	return config, errs
+}
+
+// Validate returns every invalid setting.
+func (c Config) Validate() []error {
+	var errs []error
+
+	if _, err := LoadTheme(c.Theme); err != nil {
+		errs = append(errs, fmt.Errorf("theme: %w", err))
+	}
+	if !slices.Contains(actions, strings.ToUpper(c.DefaultAction)) {
+		errs = append(errs, fmt.Errorf("default_action: %q is not one of %s", c.DefaultAction, strings.Join(actions, ", ")))
+	}
+	if !slices.Contains([]string{ConfirmAlways, ConfirmDestructive, ConfirmNever}, c.Confirm) {
+		errs = append(errs, fmt.Errorf("confirm: %q is not one of always, destructive, never", c.Confirm))
+	}
+	if d, err := time.ParseDuration(c.RefreshInterval); err != nil || d < 0 {
+		errs = append(errs, fmt.Errorf("refresh_interval: %q is not a duration such as 30s", c.RefreshInterval))
+	}
+	if d, err := time.ParseDuration(c.Revert); err != nil || d < 0 || d%time.Second != 0 {
+		errs = append(errs, fmt.Errorf("revert: %q is not a duration in seconds such as 60s", c.Revert))
+	}
+
+	if _, ok := Keymaps[c.Keymap]; !ok {
+		errs = append(errs, fmt.Errorf("keymap: %q is not one of default, vim", c.Keymap))
+	}
+	for _, err := range ValidateBindings(Bindings(c.Keymap, c.Keys)) {
+		errs = append(errs, fmt.Errorf("keys: %w", err))
+	}
+
+	if _, err := columnLayout(c.Columns); err != nil {
+		errs = append(errs, fmt.Errorf("columns: %w", err))
+	}
+
+	if _, err := utils.ParseTransport(c.Transport); err != nil {
+		errs = append(errs, fmt.Errorf("transport: %w", err))
+	}
+	if c.Groups == "" {
+		errs = append(errs, fmt.Errorf("groups: the path of the address groups file is empty"))
+	}
+	names := map[string]bool{}
+	for _, host := range c.Hosts {
+		if host.Name == "" {
+			errs = append(errs, fmt.Errorf("hosts: a host has no name"))
+		} else if names[host.Name] {
+			errs = append(errs, fmt.Errorf("hosts: %q is listed twice", host.Name))
+		}
+		names[host.Name] = true
+		if _, err := utils.ParseTransport(host.Transport); err != nil {
+			errs = append(errs, fmt.Errorf("hosts: %s: %w", host.Name, err))
+		}
+	}
+
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errs
+}
+
+// columnLayout returns the indexes of the columns to display, in order. The
+// rule number has to come first as it identifies the rule of each row.
+func columnLayout(names []string) ([]int, error) {
+	var layout []int
+	for _, name := range names {
+		index := slices.IndexFunc(columns, func(column string) bool { return strings.EqualFold(column, name) })
+		if index == -1 {
+			return nil, fmt.Errorf("unknown column %q, expected %s", name, strings.Join(columns, ", "))
+		}
+		if slices.Contains(layout, index) {
+			return nil, fmt.Errorf("column %q is listed twice", name)
+		}
+		layout = append(layout, index)
+	}
+	if len(layout) == 0 || layout[0] != 0 {
+		return nil, fmt.Errorf("the first column must be %q", columns[0])
+	}
+	return layout, nil
+}
+
+// Configure applies a validated configuration.
+func (t *Tui) Configure(config Config) {
+	t.defaultAction = strings.ToUpper(config.DefaultAction)
+	t.confirm = config.Confirm
+	t.refreshEvery, _ = time.ParseDuration(config.RefreshInterval)
+	t.revertAfter, _ = time.ParseDuration(config.Revert)
+	t.bindings = Bindings(config.Keymap, config.Keys)
+	t.mouse = config.Mouse
+	t.layout, _ = columnLayout(config.Columns)
+	transport, _ := utils.ParseTransport(config.Transport)
+	t.SetTransport(transport)
+	t.hosts = hostList(config)
+	t.SetGroupsFile(config.Groups)
+}
+
+// displayedColumns returns the indexes of the columns of the Status table.
+func (t *Tui) displayedColumns() []int {
+	if t.layout == nil {
+		layout, _ := columnLayout(columns)
+		return layout
+	}
+	return t.layout
+}
+
+// ConfigErrorScreen lists the problems found in the configuration and
+// returns whether to go on without the invalid files.
+func ConfigErrorScreen(errs []error, theme Theme) bool {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "• " + err.Error()
+	}
+
+	app := tview.NewApplication()
+	proceed := false
+
+	text := tview.NewTextView().SetText(strings.Join(lines, "\n")).SetWordWrap(true).SetTextColor(theme.Error)
+	text.SetBorderPadding(1, 1, 2, 2)
+
+	buttons := tview.NewForm().
+		AddButton("Ignore and continue", func() {
+			proceed = true
+			app.Stop()
+		}).
+		AddButton("Quit", func() { app.Stop() }).
+		SetButtonsAlign(tview.AlignCenter)
+	if !theme.Mono {
+		buttons.SetButtonBackgroundColor(theme.Button).SetButtonTextColor(theme.ButtonText)
+	}
+
+	panel := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(text, 0, 1, false).
+		AddItem(buttons, 3, 0, true)
+	panel.SetBorder(true).SetTitle(" Invalid configuration ").SetTitleColor(theme.Error)
+
+	grid := tview.NewGrid().
+		SetColumns(0, 90, 0).
+		SetRows(0, len(lines)+8, 0).
+		AddItem(panel, 1, 1, 1, 1, 0, 0, true)
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	if err := app.SetRoot(grid, true).Run(); err != nil {
+		return false
+	}
+	return proceed
+}
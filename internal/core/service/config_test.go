@@ -0,0 +1,247 @@
+package service
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	system := writeConfig(t, "theme: light\nconfirm: always\ngroups: /srv/tufw/groups.conf\nkeys:\n  add: n\n")
+	user := writeConfig(t, "confirm: never\nrefresh_interval: 30s\nrevert: 1m\ncolumns: ['#', action, to, port]\n")
+
+	config, errs := LoadConfig(system, user, filepath.Join(t.TempDir(), "missing.yaml"))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	expected := DefaultConfig()
+	expected.Theme = "light"
+	expected.Confirm = ConfirmNever
+	expected.RefreshInterval = "30s"
+	expected.Revert = "1m"
+	expected.Groups = "/srv/tufw/groups.conf"
+	expected.Keys = map[string]Keys{"add": {"n"}}
+	expected.Columns = []string{"#", "action", "to", "port"}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("got %+v, want %+v", config, expected)
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	if tui.registry.Shortcut("add") != 'n' || tui.registry.Shortcut("delete") != 'd' {
+		t.Errorf("unexpected keys %v", tui.bindings)
+	}
+	if tui.refreshEvery != 30*time.Second {
+		t.Errorf("refresh interval: got %v", tui.refreshEvery)
+	}
+	if tui.revertAfter != time.Minute {
+		t.Errorf("revert: got %v", tui.revertAfter)
+	}
+	if tui.groupsPath() != "/srv/tufw/groups.conf" {
+		t.Errorf("groups: got %v", tui.groupsPath())
+	}
+	if !reflect.DeepEqual(tui.displayedColumns(), []int{0, 3, 1, 2}) {
+		t.Errorf("layout: got %v", tui.displayedColumns())
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	valid := writeConfig(t, "default_action: deny in\n")
+	invalid := writeConfig(t, `
+theme: solarized
+default_action: DROP
+confirm: sometimes
+refresh_interval: soon
+revert: 1.5s
+keys:
+  add: d
+  edit: ee
+  launch: x
+columns: [To, Port]
+transport: telnet fw1
+groups: ""
+`)
+	typo := writeConfig(t, "colour: red\n")
+
+	config, errs := LoadConfig(valid, invalid, typo)
+
+	if config.DefaultAction != "deny in" || config.Theme != "dark" {
+		t.Errorf("only the valid files should be applied, got %+v", config)
+	}
+
+	expected := []string{
+		"columns: the first column must be",
+		"confirm:",
+		"default_action:",
+		"groups:",
+		`keys: "d" of add conflicts with "d" of delete in the menu`,
+		`keys: edit: unknown key "ee"`,
+		`keys: unknown action "launch"`,
+		"refresh_interval:",
+		"revert:",
+		"theme:",
+		`transport: "telnet" is not one of`,
+		"field colour not found",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), expected[i]) {
+			t.Errorf("error %d: %q does not mention %q", i, err, expected[i])
+		}
+	}
+	if !strings.HasPrefix(errs[0].Error(), invalid+": ") {
+		t.Errorf("errors should name their file, got %q", errs[0])
+	}
+}
+
+func TestConfigFiles_Sudo(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Under sudo, the files are the ones of the user who ran it
+	t.Setenv("SUDO_USER", current.Username)
+	expected := filepath.Join(current.HomeDir, ".config", "tufw")
+	if files := ConfigFiles(); files[len(files)-1] != filepath.Join(expected, "config.yaml") {
+		t.Errorf("got %v", files)
+	}
+	tui := CreateApplication(tcell.ColorBlue)
+	if dirs := tui.templatesDirs(); dirs[len(dirs)-1] != filepath.Join(expected, "templates") {
+		t.Errorf("got %v", dirs)
+	}
+
+	t.Setenv("SUDO_USER", "")
+	if files := ConfigFiles(); files[len(files)-1] != filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "tufw", "config.yaml") {
+		t.Errorf("got %v", files)
+	}
+}
+
+func TestColumnLayout(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return "[ 1] 192.168.0.1 22/tcp ALLOW IN 10.0.0.0/8 # Admin", "", nil
+	}
+
+	config := DefaultConfig()
+	config.Columns = []string{"#", "Action", "Port"}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.ReloadTable()
+
+	if count := tui.table.GetColumnCount(); count != 3 {
+		t.Fatalf("expected 3 columns, got %d", count)
+	}
+	var headers []string
+	for c := 0; c < 3; c++ {
+		headers = append(headers, tui.table.GetCell(0, c).Text)
+	}
+	if !reflect.DeepEqual(headers, []string{"#", "Action", "Port"}) {
+		t.Errorf("unexpected headers %v", headers)
+	}
+
+	// Hidden columns are still edited
+	tui.EditForm()
+	tui.table.SetSelectable(true, false).Select(1, 0)
+	tui.table.InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), nil)
+
+	values := tui.ParseFormValues()
+	if values.To != "192.168.0.1" || values.From != "10.0.0.0/8" || values.Comment != "Admin" {
+		t.Errorf("unexpected form values %+v", values)
+	}
+}
+
+func TestDefaultAction(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) { return "", "", nil }
+
+	config := DefaultConfig()
+	config.DefaultAction = "deny out"
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateForm()
+
+	if values := tui.ParseFormValues(); values.Action != "deny-out" {
+		t.Errorf("expected DENY OUT to be preselected, got %q", values.Action)
+	}
+	if tui.form.GetFormItemIndex("Interface") != -1 || tui.form.GetFormItemIndex("Interface out") == -1 {
+		t.Errorf("only the outgoing interface should be shown for outgoing rules")
+	}
+}
+
+func TestConfirmPolicy(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	tests := []struct {
+		policy  string
+		applied bool
+	}{
+		{ConfirmDestructive, true},
+		{ConfirmNever, true},
+		{ConfirmAlways, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			var commands []string
+			shellout = func(cmd string) (string, string, error) {
+				commands = append(commands, cmd)
+				return "", "", nil
+			}
+
+			config := DefaultConfig()
+			config.Confirm = tt.policy
+
+			tui := CreateApplication(tcell.ColorBlue)
+			tui.Configure(config)
+			tui.Init()
+			populateForm(tui.form, domain.FormValues{Port: "22", Action: "ALLOW IN"})
+			tui.CreateRule()
+
+			applied := false
+			for _, cmd := range commands {
+				applied = applied || cmd == "ufw allow in from any to any port 22"
+			}
+			if applied != tt.applied {
+				t.Errorf("applied: got %v, want %v (commands %v)", applied, tt.applied, commands)
+			}
+			if tui.pages.HasPage("modal") == tt.applied {
+				t.Errorf("a confirmation should be asked only when the rule is not applied yet")
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+const exportedFile = "Exported file"
+
+// shelloutOn runs a command on another host than the one being managed.
+var shelloutOn = utils.Transport.Shellout
+
+// compareTarget is what the rules of the host being managed are compared
+// with, another host or a file.
+type compareTarget struct {
+	name      string
+	transport *utils.Transport
+	file      string
+}
+
+// CompareForm asks for the host or file to compare the rules with.
+func (t *Tui) CompareForm() {
+	t.formHelp()
+
+	var targets []compareTarget
+	var names []string
+	for _, host := range t.hosts {
+		if t.isCurrentHost(host) {
+			continue
+		}
+		transport := t.hostTransport(host)
+		targets = append(targets, compareTarget{name: host.Name, transport: &transport})
+		names = append(names, host.Name)
+	}
+	names = append(names, exportedFile)
+
+	t.form.AddDropDown("Compare with", names, 0, nil).
+		AddInputField("File", "", 40, nil, nil).
+		AddButton("Compare", func() {
+			index, _ := t.form.GetFormItemByLabel("Compare with").(*tview.DropDown).GetCurrentOption()
+			target := compareTarget{}
+			if index < len(targets) {
+				target = targets[index]
+			} else {
+				target.file = strings.TrimSpace(t.form.GetFormItemByLabel("File").(*tview.InputField).GetText())
+				target.name = target.file
+			}
+
+			right, err := t.loadTarget(target)
+			if err != nil {
+				t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+				return
+			}
+			t.Reset()
+			t.DiffPanel(target, right)
+		}).
+		AddButton("Cancel", func() {
+			t.Reset()
+			t.app.SetFocus(t.menu)
+		})
+
+	t.secondHelp.SetText("Rules are paired by what they do, whatever their number\n\nFiles hold the output of ufw status numbered or ufw commands such as those of ufw show added").
+		SetTextColor(t.theme.Accent).
+		SetBorderPadding(0, 0, 1, 1)
+}
+
+// loadTarget returns the rules of the host or file compared with.
+func (t *Tui) loadTarget(target compareTarget) ([]domain.Rule, error) {
+	if target.transport == nil {
+		if target.file == "" {
+			return nil, fmt.Errorf("enter the path of the file to compare with")
+		}
+		content, err := os.ReadFile(target.file)
+		if err != nil {
+			return nil, err
+		}
+		return utils.ParseRulesFile(string(content)), nil
+	}
+
+	out, stderr, err := shelloutOn(*target.transport, "ufw status numbered")
+	if err != nil {
+		reason := strings.TrimSpace(stderr)
+		if reason == "" {
+			reason = err.Error()
+		}
+		return nil, fmt.Errorf("cannot read the rules of %s: %s", target.name, reason)
+	}
+	return utils.ParseRules(strings.Split(out, "\n")), nil
+}
+
+// DiffPanel shows the rules of the host being managed next to those of
+// target, paired by what they do. Rules missing on one side can be marked
+// and copied to the other.
+func (t *Tui) DiffPanel(target compareTarget, right []domain.Rule) {
+	local := t.currentHost()
+	if local == "" {
+		local = t.transport.String()
+	}
+	diffs := utils.DiffRules(t.LoadRules(), right)
+	marked := map[int]bool{}
+
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false).SetSelectedStyle(t.selectedStyle())
+	for c, column := range []string{"#", local, "#", target.name} {
+		table.SetCell(0, c, tview.NewTableCell(column).SetTextColor(t.theme.Header).SetSelectable(false))
+	}
+	onlyLeft, onlyRight := 0, 0
+	draw := func(r int) {
+		d := diffs[r]
+		background := tcell.ColorDefault
+		if marked[r] {
+			background = t.theme.Accent
+		}
+		for side, rule := range []*domain.Rule{d.Left, d.Right} {
+			number, text, color := "", "—", t.theme.Text
+			if rule != nil {
+				number, text = fmt.Sprint(rule.Number), utils.DescribeRule(*rule)
+				if d.Left == nil || d.Right == nil {
+					color = t.theme.Warning
+				}
+			}
+			table.SetCell(r+1, 2*side, tview.NewTableCell(number).SetTextColor(color).SetBackgroundColor(background))
+			table.SetCell(r+1, 2*side+1, tview.NewTableCell(text).SetTextColor(color).SetBackgroundColor(background).SetExpansion(1))
+		}
+	}
+	for r, d := range diffs {
+		draw(r)
+		switch {
+		case d.Right == nil:
+			onlyLeft++
+		case d.Left == nil:
+			onlyRight++
+		}
+	}
+	table.SetBorder(true).SetTitle(fmt.Sprintf(" %s: %d rule(s) missing, %s: %d rule(s) missing ", local, onlyRight, target.name, onlyLeft))
+
+	refresh := func() {
+		t.pages.RemovePage("diff")
+		if rules, err := t.loadTarget(target); err == nil {
+			t.DiffPanel(target, rules)
+		} else {
+			t.CreateMessage(err.Error(), func() { t.app.SetFocus(t.menu) })
+		}
+	}
+	focus := func() { t.app.SetFocus(table) }
+
+	// The rules to copy are those missing on the other side, marked or selected
+	selection := func(toRight bool) []domain.Rule {
+		rows := []int{}
+		for r := range marked {
+			rows = append(rows, r)
+		}
+		if len(rows) == 0 {
+			row, _ := table.GetSelection()
+			rows = append(rows, row-1)
+		}
+		slices.Sort(rows)
+
+		var rules []domain.Rule
+		for _, r := range rows {
+			if r < 0 || r >= len(diffs) {
+				continue
+			}
+			switch d := diffs[r]; {
+			case toRight && d.Right == nil:
+				rules = append(rules, *d.Left)
+			case !toRight && d.Left == nil:
+				rules = append(rules, *d.Right)
+			}
+		}
+		return rules
+	}
+
+	t.registry.On(PaneDiff, "mark", func() {
+		row, _ := table.GetSelection()
+		if r := row - 1; r >= 0 && r < len(diffs) && (diffs[r].Left == nil || diffs[r].Right == nil) {
+			if marked[r] {
+				delete(marked, r)
+			} else {
+				marked[r] = true
+			}
+			draw(r)
+		}
+	})
+	t.registry.On(PaneDiff, "copy-left", func() {
+		if rules := selection(false); len(rules) > 0 {
+			t.copyRules(rules, refresh, focus)
+		}
+	})
+	t.registry.On(PaneDiff, "copy-right", func() {
+		rules := selection(true)
+		switch {
+		case len(rules) == 0:
+		case target.transport == nil:
+			t.CreateMessage("Rules can only be copied to a host, the file is left as is.", focus)
+		default:
+			t.copyRulesTo(target, right, rules, refresh, focus)
+		}
+	})
+	t.registry.On(PaneDiff, "back", func() {
+		t.pages.RemovePage("diff")
+		t.app.SetFocus(t.menu)
+	})
+	t.registry.On(PaneDiff, "help", func() { t.KeysOverlay(PaneDiff) })
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return t.registry.Handle(PaneDiff, event)
+	})
+
+	help := tview.NewTextView().
+		SetText(fmt.Sprintf("<%s> Mark  <%s> Copy to %s  <%s> Copy to %s  <%s> Close  <%s> Keys",
+			t.registry.Label("mark"), t.registry.Label("copy-left"), local, t.registry.Label("copy-right"), target.name,
+			t.registry.Label("back"), t.registry.Label("help"))).
+		SetTextColor(t.theme.Accent).SetTextAlign(tview.AlignCenter)
+	panel := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(help, 1, 0, false)
+
+	grid := tview.NewGrid().
+		SetColumns(0, 140, 0).
+		SetRows(0, 30, 0).
+		AddItem(panel, 1, 1, 1, 1, 0, 0, true)
+
+	t.pages.AddPage("diff", grid, true, true)
+	focus()
+}
+
+// copyCommands returns the ufw commands adding rules. Rules matching any
+// address are added for both IP versions by a single command.
+func copyCommands(rules []domain.Rule) ([]domain.FormValues, []string) {
+	var fvs []domain.FormValues
+	var commands []string
+	for _, rule := range rules {
+		fv := utils.FormFromRule(rule)
+		command := ruleCommand(fv, 0)
+		if slices.Contains(commands, command) {
+			continue
+		}
+		fvs = append(fvs, fv)
+		commands = append(commands, command)
+	}
+	return fvs, commands
+}
+
+// copyRules adds rules to the host being managed, the way rules created in
+// the forms are.
+func (t *Tui) copyRules(rules []domain.Rule, done func(), cancel func()) {
+	fvs, commands := copyCommands(rules)
+	for _, command := range commands {
+		if _, trace, err := shellout("ufw --dry-run " + command); err != nil {
+			log.Printf("Invalid rule: %s - ufw --dry-run %s", trace, command)
+			t.CreateMessage(tview.Escape(ufwErrorMessage("ufw --dry-run "+command, trace, "")), cancel)
+			return
+		}
+	}
+
+	t.ProtectSession(func() []domain.Rule {
+		var pending []domain.Rule
+		for _, fv := range fvs {
+			pending = append(pending, utils.RuleFromForm(fv)...)
+		}
+		return utils.SpliceRules(t.LoadRules(), nil, pending)
+	}, func() {
+		failure := ""
+		t.confirmChange(fmt.Sprintf("Copy these %d rules to %s?", len(commands), t.transport), false, func() {
+			t.SafeApply(func() {
+				for _, command := range commands {
+					if _, trace, err := shellout("ufw " + command); err != nil {
+						log.Printf("Failed to copy rule: %s - ufw %s", trace, command)
+						failure = ufwErrorMessage("ufw "+command, trace, "")
+						return
+					}
+					log.Printf("Copying rule: ufw %s", command)
+				}
+			})
+		}, cancel, func() {
+			t.pages.RemovePage("modal")
+			t.reportCopy(failure, done)
+		})
+	}, cancel)
+}
+
+// copyRulesTo adds rules to the host compared with, where rules are the
+// rules found. There is no revert timer there, the ssh session tufw would
+// run in is protected all the same.
+func (t *Tui) copyRulesTo(target compareTarget, rules []domain.Rule, copied []domain.Rule, done func(), cancel func()) {
+	transport := *target.transport
+	fvs, commands := copyCommands(copied)
+	for _, command := range commands {
+		if _, trace, err := shelloutOn(transport, "ufw --dry-run "+command); err != nil {
+			log.Printf("Invalid rule on %s: %s - ufw --dry-run %s", target.name, trace, command)
+			t.CreateMessage(tview.Escape(ufwErrorMessage("ufw --dry-run "+command, trace, "")), cancel)
+			return
+		}
+	}
+
+	apply := func() {
+		failure := ""
+		t.confirmChange(fmt.Sprintf("Copy these %d rules to %s?", len(commands), target.name), false, func() {
+			for _, command := range commands {
+				if _, trace, err := shelloutOn(transport, "ufw "+command); err != nil {
+					log.Printf("Failed to copy rule to %s: %s - ufw %s", target.name, trace, command)
+					failure = ufwErrorMessage("ufw "+command, trace, "")
+					return
+				}
+				log.Printf("Copying rule to %s: ufw %s", target.name, command)
+			}
+		}, cancel, func() {
+			t.pages.RemovePage("modal")
+			t.reportCopy(failure, done)
+		})
+	}
+
+	if !transport.Remote() {
+		apply()
+		return
+	}
+	out, _, _ := shelloutOn(transport, "echo $SSH_CONNECTION")
+	session := sessionInterface(utils.ParseSSHConnection(strings.TrimSpace(out)), func(cmd string) (string, string, error) {
+		return shelloutOn(transport, cmd)
+	})
+	if session == nil {
+		apply()
+		return
+	}
+	status, _, _ := shelloutOn(transport, "ufw status verbose")
+	var pending []domain.Rule
+	for _, fv := range fvs {
+		pending = append(pending, utils.RuleFromForm(fv)...)
+	}
+	t.protectSessionOf(*session, utils.SpliceRules(rules, nil, pending), utils.ParseStatus(status).Defaults, apply, cancel)
+}
+
+// reportCopy tells why a copy stopped, if it did, before running done.
+func (t *Tui) reportCopy(failure string, done func()) {
+	if failure == "" {
+		done()
+		return
+	}
+	t.CreateMessage(tview.Escape(failure), done)
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+func TestDiffPanel(t *testing.T) {
+	origShellout, origShelloutOn := shellout, shelloutOn
+	defer func() { shellout, shelloutOn = origShellout, origShelloutOn }()
+
+	var local, remote []string
+	shellout = func(cmd string) (string, string, error) {
+		local = append(local, cmd)
+		if strings.HasPrefix(cmd, "ufw status numbered") {
+			return "[ 1] 22/tcp ALLOW IN Anywhere\n[ 2] 80/tcp ALLOW IN Anywhere", "", nil
+		}
+		return "", "", nil
+	}
+	shelloutOn = func(transport utils.Transport, cmd string) (string, string, error) {
+		remote = append(remote, transport.Prefix+": "+cmd)
+		if cmd == "ufw status numbered" {
+			return "Status: active\n\n     To    Action    From\n[ 1] 80/tcp ALLOW IN Anywhere\n[ 2] 443/tcp ALLOW IN 10.0.0.0/8", "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	config := DefaultConfig()
+	config.Hosts = []Host{{Name: "fw1", Transport: "ssh fw1"}}
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+
+	target := compareTarget{name: "fw1", transport: &utils.Transport{Prefix: "ssh fw1"}}
+	right, err := tui.loadTarget(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tui.DiffPanel(target, right)
+
+	table, ok := tui.app.GetFocus().(*tview.Table)
+	if !ok {
+		t.Fatalf("expected the diff to be focused")
+	}
+	cell := func(row, column int) string { return table.GetCell(row, column).Text }
+	// 22 is only local, 80 is on both sides despite its number, 443 is only on fw1
+	expected := [][]string{
+		{"1", "ALLOW IN 22/tcp from any", "", "—"},
+		{"2", "ALLOW IN 80/tcp from any", "1", "ALLOW IN 80/tcp from any"},
+		{"", "—", "2", "ALLOW IN 443/tcp from 10.0.0.0/8"},
+	}
+	for r, row := range expected {
+		for c, text := range row {
+			if got := cell(r+1, c); got != text {
+				t.Errorf("row %d, column %d: got %q, want %q", r+1, c, got, text)
+			}
+		}
+	}
+
+	press := func(ch rune) {
+		table.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, ch, tcell.ModNone))
+	}
+
+	// Rules on both sides are neither marked nor copied
+	table.Select(2, 0)
+	press(' ')
+	press('>')
+	if len(remote) != 1 {
+		t.Errorf("nothing should be copied, got %v", remote)
+	}
+
+	table.Select(1, 0)
+	press(' ')
+	press('>')
+	if !contains(remote, "ssh fw1: ufw --dry-run allow", "port 22") || !contains(remote, "ssh fw1: ufw allow", "port 22") {
+		t.Errorf("22/tcp should be copied to fw1, got %v", remote)
+	}
+
+	table, _ = tui.app.GetFocus().(*tview.Table)
+	table.Select(3, 0)
+	table.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, '<', tcell.ModNone))
+	if !contains(local, "ufw allow", "from 10.0.0.0/8 to any proto tcp port 443") {
+		t.Errorf("443/tcp should be copied locally, got %v", local)
+	}
+}
+
+func TestDiffPanel_File(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ufw status numbered") {
+			return "[ 1] 22/tcp ALLOW IN Anywhere", "", nil
+		}
+		return "", "", nil
+	}
+
+	file := filepath.Join(t.TempDir(), "rules")
+	os.WriteFile(file, []byte("ufw allow 22/tcp\nufw allow from 10.0.0.0/8 to any port 3306 proto tcp\n"), 0644)
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateLayout()
+	tui.CompareForm()
+	tui.form.GetFormItemByLabel("File").(*tview.InputField).SetText(file)
+	tui.form.GetButton(0).InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), nil)
+
+	table, ok := tui.app.GetFocus().(*tview.Table)
+	if !ok {
+		t.Fatalf("expected the diff to be shown, got %q", tui.secondHelp.GetText(true))
+	}
+	// The file holds a v6 version of 22/tcp and the 3306 rule the host lacks
+	if title := table.GetTitle(); !strings.Contains(title, "local: 2 rule(s) missing") {
+		t.Errorf("unexpected title %q", title)
+	}
+
+	table.Select(1, 0)
+	table.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, '>', tcell.ModNone))
+	if name, _ := tui.pages.GetFrontPage(); name == "message" {
+		t.Errorf("only rules missing on the other side should be copied")
+	}
+}
+
+func TestDiffPanel_Keys(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ufw status numbered") {
+			return "[ 1] 80/tcp ALLOW IN Anywhere", "", nil
+		}
+		return "", "", nil
+	}
+
+	file := filepath.Join(t.TempDir(), "rules")
+	os.WriteFile(file, []byte("ufw allow 22/tcp\n"), 0644)
+
+	config := DefaultConfig()
+	config.Keys = map[string]Keys{"copy-right": {"c"}}
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+
+	target := compareTarget{name: file, file: file}
+	right, err := tui.loadTarget(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tui.DiffPanel(target, right)
+
+	table := tui.app.GetFocus().(*tview.Table)
+	press := func(ch rune) {
+		table.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, ch, tcell.ModNone))
+	}
+
+	table.Select(1, 0)
+	press('>')
+	if name, _ := tui.pages.GetFrontPage(); name != "diff" {
+		t.Errorf("> should no longer copy once copy-right is bound to c")
+	}
+	press('c')
+	if name, _ := tui.pages.GetFrontPage(); name != "message" {
+		t.Errorf("c should try to copy the rule to the file")
+	}
+	tui.pages.RemovePage("message")
+
+	press('?')
+	if name, _ := tui.pages.GetFrontPage(); name != "keys" {
+		t.Errorf("? should list the keys of the comparison")
+	}
+}
+
+func contains(commands []string, prefix string, part string) bool {
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd, prefix) && strings.Contains(cmd, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+func cellValue(cellValues *domain.CellValues, column int) string {
+	switch column {
+	case 0: // "#"
+		return cellValues.Index
+	case 1: // "To"
+		return cellValues.To
+	case 2: // "Port"
+		return cellValues.Port
+	case 3: // "Action"
+		return cellValues.Action
+	case 4: // "From"
+		return cellValues.From
+	case 5: // "Interface"
+		return cellValues.Interface
+	case 6: // "Comment"
+		return cellValues.Comment
+	}
+	return ""
+}
+
+// firstPort returns the first port of a port list or range, used to sort them numerically.
+func firstPort(port string) (int, bool) {
+	if idx := strings.IndexAny(port, ",:"); idx != -1 {
+		port = port[:idx]
+	}
+	n, err := strconv.Atoi(port)
+	return n, err == nil
+}
+
+func lessCells(a, b *domain.CellValues, column int) bool {
+	switch column {
+	case 0:
+		return indexNumber(a.Index) < indexNumber(b.Index)
+	case 2:
+		pa, okA := firstPort(a.Port)
+		pb, okB := firstPort(b.Port)
+		if okA && okB {
+			return pa < pb
+		}
+		// Rules without a port come last
+		if okA != okB {
+			return okA
+		}
+	}
+	return strings.ToLower(cellValue(a, column)) < strings.ToLower(cellValue(b, column))
+}
+
+func (t *Tui) sortCells(cells []*domain.CellValues) {
+	sort.SliceStable(cells, func(i, j int) bool {
+		if t.sortDesc {
+			return lessCells(cells[j], cells[i], t.sortColumn)
+		}
+		return lessCells(cells[i], cells[j], t.sortColumn)
+	})
+}
+
+func (t *Tui) sortIndicator(column int) string {
+	if column != t.sortColumn || (column == 0 && !t.sortDesc) {
+		return ""
+	}
+	if t.sortDesc {
+		return " ▼"
+	}
+	return " ▲"
+}
+
+// SortBy sorts the Status table by the given column, reversing the order
+// when it is already sorted by it. Rule numbers are kept as they are so
+// editing and deleting still target the right rules.
+func (t *Tui) SortBy(column int) {
+	if column == t.sortColumn {
+		t.sortDesc = !t.sortDesc
+	} else {
+		t.sortColumn = column
+		t.sortDesc = false
+	}
+
+	t.ReloadTable()
+}
+
+func matchesFilter(column int, value string, filter string) bool {
+	switch column {
+	case 2: // "Port"
+		if utils.IsPortSpec(value) && utils.IsPortSpec(filter) {
+			return utils.PortContains(value, filter)
+		}
+	case 3: // "Action"
+		return strings.HasPrefix(value, strings.ToUpper(filter))
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(filter))
+}
+
+func (t *Tui) matchesFilters(cellValues *domain.CellValues) bool {
+	for column, filter := range t.filters {
+		if !matchesFilter(column, cellValue(cellValues, column), filter) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetFilters only displays the rules whose columns match the given filters, keyed by column.
+func (t *Tui) SetFilters(filters map[int]string) {
+	t.filters = map[int]string{}
+	for column, filter := range filters {
+		if filter = strings.TrimSpace(filter); filter != "" {
+			t.filters[column] = filter
+		}
+	}
+
+	t.ReloadTable()
+}
+
+func (t *Tui) FilterForm() {
+	t.formHelp()
+	interfaces, _ := t.LoadInterfaces()
+
+	actionOptions := []string{"", "ALLOW", "DENY", "REJECT", "LIMIT"}
+	actionIndex := 0
+	for i, action := range actionOptions {
+		if action == t.filters[3] {
+			actionIndex = i
+		}
+	}
+
+	t.form.AddInputField("To", t.filters[1], 20, nil, nil).
+		AddInputField("Port", t.filters[2], 20, nil, nil).
+		AddDropDown("Action", actionOptions, actionIndex, nil).
+		AddInputField("From", t.filters[4], 20, nil, nil).
+		AddDropDown("Interface", interfaces, utils.ParseInterfaceIndex(t.filters[5], interfaces), nil).
+		AddInputField("Comment", t.filters[6], 40, nil, nil).
+		AddButton("Apply", func() {
+			_, action := t.form.GetFormItemByLabel("Action").(*tview.DropDown).GetCurrentOption()
+			_, iface := t.form.GetFormItemByLabel("Interface").(*tview.DropDown).GetCurrentOption()
+			t.SetFilters(map[int]string{
+				1: t.form.GetFormItemByLabel("To").(*tview.InputField).GetText(),
+				2: t.form.GetFormItemByLabel("Port").(*tview.InputField).GetText(),
+				3: action,
+				4: t.form.GetFormItemByLabel("From").(*tview.InputField).GetText(),
+				5: iface,
+				6: t.form.GetFormItemByLabel("Comment").(*tview.InputField).GetText(),
+			})
+			t.Reset()
+			t.help.SetText("Press <Esc> to go back to the menu selection").SetBorderPadding(1, 0, 1, 0)
+			t.app.SetFocus(t.table)
+		}).
+		AddButton("Clear", func() {
+			t.SetFilters(nil)
+			t.Reset()
+			t.app.SetFocus(t.menu)
+		})
+
+	t.secondHelp.SetText(fmt.Sprintf("Only rules matching every filled field are shown\n\nPress <1> to <%d> in the table to sort it by column", len(columns))).
+		SetTextColor(t.theme.Accent).
+		SetBorderPadding(0, 0, 1, 1)
+}
+
+// affectingRows keeps the status rows whose rule can apply to traffic
+// involving address on port with proto, in evaluation order.
+func (t *Tui) affectingRows(rows []string, rules []domain.Rule, address, port, proto string) ([]string, error) {
+	if address != "" && !utils.IsAddress(address) {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+	if port != "" && !utils.IsPortSpec(port) {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+
+	affecting := map[int]bool{}
+	for _, rule := range rules {
+		if utils.RuleAffects(rule, address, port, proto) {
+			affecting[rule.Number] = true
+		}
+	}
+
+	var matches []string
+	for _, row := range rows {
+		if rule := utils.ParseRule(row); rule != nil && affecting[rule.Number] {
+			matches = append(matches, row)
+		}
+	}
+	return matches, nil
+}
+
+// showAffecting lists the rules affecting the given traffic in the order ufw
+// evaluates them, so the first one listed is the one deciding.
+func (t *Tui) showAffecting(rows []string, rules []domain.Rule, address, port, proto string) {
+	matches, err := t.affectingRows(rows, rules, address, port, proto)
+	if err != nil {
+		t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+		return
+	}
+
+	t.sortColumn, t.sortDesc = 0, false
+	t.table.Clear()
+	t.CreateTable(matches)
+
+	if len(matches) == 0 {
+		t.secondHelp.SetText(" No rule affects this traffic, the default policy applies.").SetTextColor(t.theme.Accent)
+		return
+	}
+	t.secondHelp.SetText(fmt.Sprintf(" %d rule(s) affect this traffic, in evaluation order.\n\n Rule %s is evaluated first.",
+		len(matches), t.table.GetCell(1, 0).Text)).SetTextColor(t.theme.Accent)
+}
+
+func (t *Tui) AffectsForm() {
+	t.help.SetText("Find the rules affecting an address and/or a port").SetBorderPadding(1, 0, 1, 1)
+
+	rows, _ := t.LoadUFWOutput()
+	rules := t.resolveApps(utils.ParseRules(rows))
+
+	update := func(string) {
+		_, proto := t.form.GetFormItemByLabel("Protocol").(*tview.DropDown).GetCurrentOption()
+		t.showAffecting(rows, rules,
+			strings.TrimSpace(t.form.GetFormItemByLabel("Address").(*tview.InputField).GetText()),
+			strings.TrimSpace(t.form.GetFormItemByLabel("Port").(*tview.InputField).GetText()),
+			proto,
+		)
+	}
+
+	t.form.AddInputField("Address", "", 40, nil, nil).
+		AddInputField("Port", "", 20, nil, nil).
+		AddDropDown("Protocol", []string{"", "tcp", "udp"}, 0, nil).
+		AddButton("Show", func() { t.app.SetFocus(t.table) }).
+		AddButton("Cancel", func() {
+			t.Reset()
+			t.ReloadTable()
+			t.app.SetFocus(t.menu)
+		})
+
+	t.form.GetFormItemByLabel("Address").(*tview.InputField).SetChangedFunc(update)
+	t.form.GetFormItemByLabel("Port").(*tview.InputField).SetChangedFunc(update)
+	t.form.GetFormItemByLabel("Protocol").(*tview.DropDown).SetSelectedFunc(func(string, int) { update("") })
+
+	t.secondHelp.SetText("Address can be an IP or a CIDR, Port a single port or a range").SetTextColor(t.theme.Accent).SetBorderPadding(0, 0, 1, 1)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/utils"
+)
+
+var statusRows = []string{
+	"[ 1] 22/tcp ALLOW IN Anywhere",
+	"[ 2] 443/tcp DENY IN 10.0.0.0/8",
+	"[ 3] Anywhere on wg0 ALLOW IN Anywhere # VPN",
+	"[ 4] 80,443/tcp DENY IN Anywhere",
+	"[ 5] 1.1.1.1 53 ALLOW OUT Anywhere on eth0 (out)",
+}
+
+func columnTexts(tui *Tui, column int) []string {
+	var texts []string
+	for row := 1; row < tui.table.GetRowCount(); row++ {
+		texts = append(texts, tui.table.GetCell(row, column).Text)
+	}
+	return texts
+}
+
+func TestSortBy(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return strings.Join(statusRows, "\n"), "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+
+	tui.SortBy(2)
+	if got := columnTexts(tui, 0); !reflect.DeepEqual(got, []string{"[1]", "[5]", "[4]", "[2]", "[3]"}) {
+		t.Errorf("expected rules sorted by port, got %v", got)
+	}
+	if header := tui.table.GetCell(0, 2).Text; header != "Port ▲" {
+		t.Errorf("expected sort indicator on the Port header, got %q", header)
+	}
+
+	tui.SortBy(2)
+	if got := columnTexts(tui, 0); !reflect.DeepEqual(got, []string{"[3]", "[2]", "[4]", "[5]", "[1]"}) {
+		t.Errorf("expected rules sorted by descending port, got %v", got)
+	}
+
+	// Actions and deletions target the real rule number, not the table row
+	if n := tui.RuleNumber(1); n != 3 {
+		t.Errorf("expected first row to be rule 3, got %d", n)
+	}
+}
+
+func TestSetFilters(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return strings.Join(statusRows, "\n"), "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+
+	tests := []struct {
+		name     string
+		filters  map[int]string
+		expected []string
+	}{
+		{"only deny", map[int]string{3: "DENY"}, []string{"[2]", "[4]"}},
+		{"only port 443", map[int]string{2: "443"}, []string{"[2]", "[4]"}},
+		{"only wg0", map[int]string{5: "wg0"}, []string{"[3]"}},
+		{"deny from subnet", map[int]string{3: "DENY", 4: "10.0.0"}, []string{"[2]"}},
+		{"no filter", map[int]string{3: ""}, []string{"[1]", "[2]", "[3]", "[4]", "[5]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tui.SetFilters(tt.filters)
+			if got := columnTexts(tui, 0); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected rules %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+
+	if !tui.search(statusRows, "action:deny port:443") {
+		t.Fatal("expected the query to match")
+	}
+	if got := columnTexts(tui, 0); !reflect.DeepEqual(got, []string{"[2]", "[4]"}) {
+		t.Errorf("expected deny rules on 443, got %v", got)
+	}
+
+	if tui.search(statusRows, "(iface:wg0 OR") {
+		t.Error("expected an incomplete query to be rejected")
+	}
+	if got := columnTexts(tui, 0); !reflect.DeepEqual(got, []string{"[2]", "[4]"}) {
+		t.Errorf("expected an invalid query to keep the previous results, got %v", got)
+	}
+
+	tui.search(statusRows, "iface:wg0 OR NOT dir:in")
+	if got := columnTexts(tui, 0); !reflect.DeepEqual(got, []string{"[3]", "[5]"}) {
+		t.Errorf("expected rules on wg0 or outbound, got %v", got)
+	}
+}
+
+func TestShowAffecting(t *testing.T) {
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.sortColumn, tui.sortDesc = 2, true
+
+	rules := utils.ParseRules(statusRows)
+	tui.showAffecting(statusRows, rules, "10.0.0.5", "443", "tcp")
+	if got := columnTexts(tui, 0); !reflect.DeepEqual(got, []string{"[2]", "[3]", "[4]"}) {
+		t.Errorf("expected affecting rules in evaluation order, got %v", got)
+	}
+
+	if _, err := tui.affectingRows(statusRows, rules, "10.0.0.500", "", ""); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+}
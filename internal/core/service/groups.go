@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+const defaultGroupsFile = "/etc/tufw/groups.conf"
+
+// SetGroupsFile changes the file the address groups are stored in.
+func (t *Tui) SetGroupsFile(path string) {
+	t.groupsFile = path
+}
+
+func (t *Tui) groupsPath() string {
+	if t.groupsFile == "" {
+		return defaultGroupsFile
+	}
+	return t.groupsFile
+}
+
+// LoadGroups reads the address groups, none being defined when the file does not exist.
+func (t *Tui) LoadGroups() (map[string][]string, error) {
+	content, err := os.ReadFile(t.groupsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := utils.ParseGroups(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.groupsPath(), err)
+	}
+	return groups, nil
+}
+
+func (t *Tui) SaveGroups(groups map[string][]string) error {
+	if err := os.MkdirAll(filepath.Dir(t.groupsPath()), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.groupsPath(), []byte(utils.FormatGroups(groups)), 0o644)
+}
+
+// CreateGroupRules adds one rule per member of the groups used in To and From.
+func (t *Tui) CreateGroupRules(fv domain.FormValues) {
+	expanded, err := t.expandGroups(fv)
+	if err != nil {
+		log.Printf("Invalid rule: %v", err)
+		t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+		return
+	}
+
+	commands := make([]string, len(expanded))
+	for i, rule := range expanded {
+		commands[i] = ruleCommand(rule, 0)
+	}
+	t.applyRules(nil, expanded, commands, func() {})
+}
+
+func (t *Tui) expandGroups(fv domain.FormValues) ([]domain.FormValues, error) {
+	groups, err := t.LoadGroups()
+	if err != nil {
+		return nil, err
+	}
+	return utils.ExpandGroups(fv, groups)
+}
+
+// applyRules removes the rules numbered remove and runs the ufw commands
+// adding rules, once they all passed a dry run. applied runs once they all
+// succeeded; when one fails partway, the user is told which part was applied.
+func (t *Tui) applyRules(remove []int, rules []domain.FormValues, commands []string, applied func()) {
+	for _, command := range commands {
+		if _, trace, err := shellout("ufw --dry-run " + command); err != nil {
+			log.Printf("Invalid rule: %s - ufw --dry-run %s", trace, command)
+			t.ShowUfwError("ufw --dry-run "+command, trace)
+			return
+		}
+	}
+
+	partial := ""
+	t.ProtectSession(func() []domain.Rule {
+		var pending []domain.Rule
+		for _, rule := range rules {
+			pending = append(pending, utils.RuleFromForm(rule)...)
+		}
+		return utils.SpliceRules(t.LoadRules(), remove, pending)
+	}, func() {
+		text := fmt.Sprintf("Add these %d rules?", len(commands))
+		if len(remove) > 0 {
+			text = fmt.Sprintf("Replace %d rules with these %d?", len(remove), len(commands))
+		}
+		t.confirmChange(text, false, func() {
+			t.SafeApply(func() {
+				deleted, failed := t.RemoveRules(remove)
+				if len(failed) > 0 {
+					log.Printf("Failed to remove %d of %d rules, none added", len(failed), len(remove))
+					partial = "The changes were only partly applied, no rule was added.\n\n" + removalSummary(deleted, failed)
+					t.ReloadTable()
+					return
+				}
+				for i, command := range commands {
+					if _, trace, err := shellout("ufw " + command); err != nil {
+						log.Printf("Failed to apply rule: %s - ufw %s", trace, command)
+						if len(deleted) == 0 && i == 0 {
+							t.ShowUfwError("ufw "+command, trace)
+							return
+						}
+						partial = fmt.Sprintf("The changes were only partly applied: %d rule(s) deleted and %d of %d added.\n\n%s",
+							len(deleted), i, len(commands), ufwErrorMessage("ufw "+command, trace, ""))
+						t.ReloadTable()
+						return
+					}
+					log.Printf("Creating rule: ufw %s", command)
+				}
+				applied()
+
+				t.Reset()
+				t.ReloadTable()
+			})
+		}, func() {
+			t.app.SetFocus(t.form)
+		}, func() {
+			t.pages.RemovePage("modal")
+			if partial != "" {
+				t.CreateMessage(tview.Escape(partial), func() {
+					if t.form.GetFormItemCount() == 0 {
+						t.app.SetFocus(t.table)
+						return
+					}
+					t.app.SetFocus(t.form)
+				})
+			}
+		})
+	}, func() {
+		t.app.SetFocus(t.form)
+	})
+}
+
+// SyncGroup replaces the rules derived from a group when its members change.
+// They are re-created where the first IPv4 one was, IPv6 ones being appended
+// as ufw lists them after IPv4 rules. synced runs once they are, or right
+// away when no rule is derived from the group.
+func (t *Tui) SyncGroup(name string, previous []string, groups map[string][]string, synced func()) {
+	rules := t.LoadRules()
+	templates, numbers := utils.GroupTemplates(rules, name, previous)
+	if len(numbers) == 0 {
+		synced()
+		return
+	}
+
+	removed := map[int]bool{}
+	position := 0
+	for _, rule := range rules {
+		if slices.Contains(numbers, rule.Number) {
+			removed[rule.Number] = true
+			if !rule.V6 && position == 0 {
+				position = rule.Number
+			}
+		}
+	}
+	// Inserting is only possible before a remaining IPv4 rule
+	remaining := slices.ContainsFunc(rules, func(rule domain.Rule) bool {
+		return !rule.V6 && !removed[rule.Number] && rule.Number > position
+	})
+	if !remaining {
+		position = 0
+	}
+
+	var expanded []domain.FormValues
+	var commands []string
+	for _, template := range templates {
+		members, err := utils.ExpandGroups(template, groups)
+		if err != nil {
+			log.Printf("Skipping rule derived from %s: %v", name, err)
+			continue
+		}
+		for _, rule := range members {
+			insert := 0
+			if position > 0 && !utils.RuleFromForm(rule)[0].V6 {
+				insert = position
+				position++
+			}
+			expanded = append(expanded, rule)
+			commands = append(commands, ruleCommand(rule, insert))
+		}
+	}
+
+	t.applyRules(numbers, expanded, commands, synced)
+}
+
+func (t *Tui) GroupsForm() {
+	t.formHelp()
+
+	groups, err := t.LoadGroups()
+	if err != nil {
+		t.CreateMessage(err.Error(), func() { t.app.SetFocus(t.menu) })
+		return
+	}
+
+	names := []string{"New group"}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+
+	t.form.AddDropDown("Group", names, 0, nil).
+		AddInputField("Name", "@", 20, nil, nil).
+		AddInputField("Members", "", 60, nil, nil).
+		AddButton("Save", func() {
+			name := strings.TrimSpace(t.form.GetFormItemByLabel("Name").(*tview.InputField).GetText())
+			if !utils.IsGroup(name) {
+				t.secondHelp.SetText(" Group names look like @office").SetTextColor(t.theme.Error)
+				return
+			}
+			members, err := utils.ParseMembers(t.form.GetFormItemByLabel("Members").(*tview.InputField).GetText())
+			if err != nil {
+				t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+				return
+			}
+
+			previous := groups[name]
+			updated := maps.Clone(groups)
+			updated[name] = members
+			if previous == nil || slices.Equal(previous, members) {
+				if err := t.SaveGroups(updated); err != nil {
+					t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+					return
+				}
+				log.Printf("Saved address group %s = %s", name, strings.Join(members, ", "))
+				t.Reset()
+				t.app.SetFocus(t.menu)
+				return
+			}
+
+			// The file keeps the previous members unless the rules are updated
+			t.Reset()
+			t.app.SetFocus(t.menu)
+			t.SyncGroup(name, previous, updated, func() {
+				if err := t.SaveGroups(updated); err != nil {
+					log.Printf("Failed to save address group %s: %v", name, err)
+					t.CreateMessage(fmt.Sprintf("The rules of %s were updated but the group could not be saved: %v", name, err), func() {
+						t.app.SetFocus(t.menu)
+					})
+					return
+				}
+				log.Printf("Saved address group %s = %s", name, strings.Join(members, ", "))
+			})
+		}).
+		AddButton("Delete", func() {
+			name := strings.TrimSpace(t.form.GetFormItemByLabel("Name").(*tview.InputField).GetText())
+			if _, ok := groups[name]; !ok {
+				return
+			}
+			if _, numbers := utils.GroupTemplates(t.LoadRules(), name, groups[name]); len(numbers) > 0 {
+				t.secondHelp.SetText(fmt.Sprintf(" %s is still used by %d rule(s)", name, len(numbers))).SetTextColor(t.theme.Error)
+				return
+			}
+
+			delete(groups, name)
+			if err := t.SaveGroups(groups); err != nil {
+				t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+				return
+			}
+			t.Reset()
+			t.app.SetFocus(t.menu)
+		}).
+		AddButton("Cancel", func() {
+			t.Reset()
+			t.app.SetFocus(t.menu)
+		})
+
+	t.form.GetFormItemByLabel("Group").(*tview.DropDown).SetSelectedFunc(func(name string, index int) {
+		if index == 0 {
+			name = "@"
+		}
+		t.form.GetFormItemByLabel("Name").(*tview.InputField).SetText(name)
+		t.form.GetFormItemByLabel("Members").(*tview.InputField).SetText(strings.Join(groups[name], ", "))
+	})
+
+	t.secondHelp.SetText("Use @name in the To and From fields to add one rule per member\n\nEditing a group updates the rules created from it").
+		SetTextColor(t.theme.Accent).
+		SetBorderPadding(0, 0, 1, 1)
+}
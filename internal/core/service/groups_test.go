@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/rivo/tview"
+)
+
+func TestCreateGroupRules(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ufw --dry-run") || strings.HasPrefix(cmd, "ufw allow") {
+			commands = append(commands, cmd)
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetGroupsFile(filepath.Join(t.TempDir(), "groups.conf"))
+	tui.Init()
+	tui.CreateLayout()
+
+	if err := tui.SaveGroups(map[string][]string{"@office": {"203.0.113.0/24", "198.51.100.7"}}); err != nil {
+		t.Fatal(err)
+	}
+	tui.CreateGroupRules(domain.FormValues{Port: "22", Protocol: "tcp", Action: "ALLOW IN", From: "@office", Comment: "SSH"})
+
+	expected := []string{
+		"ufw --dry-run allow in from 203.0.113.0/24 to any proto tcp port 22 comment 'SSH @office'",
+		"ufw --dry-run allow in from 198.51.100.7 to any proto tcp port 22 comment 'SSH @office'",
+		"ufw allow in from 203.0.113.0/24 to any proto tcp port 22 comment 'SSH @office'",
+		"ufw allow in from 198.51.100.7 to any proto tcp port 22 comment 'SSH @office'",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands:\n%q\nbut got:\n%q", expected, commands)
+	}
+}
+
+func TestSyncGroup(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	rows := []string{
+		"[ 1] 80/tcp ALLOW IN Anywhere",
+		"[ 2] 22 ALLOW IN 203.0.113.0/24 # SSH @office",
+		"[ 3] 22 ALLOW IN 198.51.100.7 # SSH @office",
+		"[ 4] 443 ALLOW IN Anywhere",
+	}
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ufw status numbered") {
+			return strings.Join(rows, "\n"), "", nil
+		}
+		if strings.HasPrefix(cmd, "ufw --") || strings.HasPrefix(cmd, "ufw insert") {
+			commands = append(commands, cmd)
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetGroupsFile(filepath.Join(t.TempDir(), "groups.conf"))
+	tui.Init()
+	tui.CreateLayout()
+
+	groups := map[string][]string{"@office": {"203.0.113.0/24", "192.0.2.9"}}
+	synced := false
+	tui.SyncGroup("@office", []string{"203.0.113.0/24", "198.51.100.7"}, groups, func() { synced = true })
+
+	expected := []string{
+		"ufw --dry-run insert 2 allow in from 203.0.113.0/24 to any port 22 comment 'SSH @office'",
+		"ufw --dry-run insert 3 allow in from 192.0.2.9 to any port 22 comment 'SSH @office'",
+		"ufw --force delete 3",
+		"ufw --force delete 2",
+		"ufw insert 2 allow in from 203.0.113.0/24 to any port 22 comment 'SSH @office'",
+		"ufw insert 3 allow in from 192.0.2.9 to any port 22 comment 'SSH @office'",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands:\n%q\nbut got:\n%q", expected, commands)
+	}
+	if !synced {
+		t.Errorf("expected the rules to be reported as synced")
+	}
+}
+
+func TestSyncGroup_PartlyApplied(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	refused := ""
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		switch {
+		case strings.HasPrefix(cmd, "ufw status numbered"):
+			return "[ 1] 22 ALLOW IN 203.0.113.0/24 # SSH @office\n[ 2] 22 ALLOW IN 198.51.100.7 # SSH @office", "", nil
+		case strings.HasPrefix(cmd, refused):
+			return "", "ERROR: Could not delete non-existent rule", errors.New("exit status 1")
+		case strings.HasPrefix(cmd, "ufw insert") || strings.HasPrefix(cmd, "ufw allow"):
+			commands = append(commands, cmd)
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetGroupsFile(filepath.Join(t.TempDir(), "groups.conf"))
+	tui.Init()
+	tui.CreateLayout()
+
+	groups := map[string][]string{"@office": {"192.0.2.9"}}
+	previous := []string{"203.0.113.0/24", "198.51.100.7"}
+	message := func() string {
+		if !tui.pages.HasPage("message") {
+			return ""
+		}
+		defer tui.pages.RemovePage("message")
+		_, modal := tui.pages.GetFrontPage()
+		screen := tcell.NewSimulationScreen("")
+		screen.Init()
+		screen.SetSize(600, 40)
+		modal.SetRect(0, 0, 600, 40)
+		modal.Draw(screen)
+		screen.Show()
+		cells, _, _ := screen.GetContents()
+		var text strings.Builder
+		for _, cell := range cells {
+			text.WriteString(string(cell.Runes))
+		}
+		return strings.Join(strings.Fields(text.String()), " ")
+	}
+
+	refused = "ufw --force delete 1"
+	synced := false
+	tui.SyncGroup("@office", previous, groups, func() { synced = true })
+	if text := message(); !strings.Contains(text, "only partly applied, no rule was added") || !strings.Contains(text, "1 rule(s) deleted") {
+		t.Errorf("expected the failed removal to be reported, got %q", text)
+	}
+	if synced || len(commands) > 0 {
+		t.Errorf("no rule should be added once a removal failed, got %q", commands)
+	}
+
+	refused = "ufw allow"
+	tui.SyncGroup("@office", previous, groups, func() { synced = true })
+	if text := message(); !strings.Contains(text, "only partly applied: 2 rule(s) deleted and 0 of 1 added") {
+		t.Errorf("expected the failed addition to be reported, got %q", text)
+	}
+	if synced {
+		t.Errorf("the rules should not be reported as synced")
+	}
+}
+
+func TestGroupsForm_Save(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	refused := true
+	shellout = func(cmd string) (string, string, error) {
+		switch {
+		case strings.HasPrefix(cmd, "ufw status numbered"):
+			return "[ 1] 22 ALLOW IN 203.0.113.0/24 # SSH @office", "", nil
+		case refused && strings.HasPrefix(cmd, "ufw --dry-run"):
+			return "", "ERROR: Bad source address", errors.New("exit status 1")
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetGroupsFile(filepath.Join(t.TempDir(), "groups.conf"))
+	tui.Init()
+	tui.CreateLayout()
+	if err := tui.SaveGroups(map[string][]string{"@office": {"203.0.113.0/24"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	save := func() {
+		tui.Reset()
+		tui.GroupsForm()
+		tui.form.GetFormItemByLabel("Name").(*tview.InputField).SetText("@office")
+		tui.form.GetFormItemByLabel("Members").(*tview.InputField).SetText("192.0.2.9")
+		tui.form.GetButton(0).InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), nil)
+	}
+
+	// The members are kept as long as the rules derived from them are
+	save()
+	if groups, _ := tui.LoadGroups(); !reflect.DeepEqual(groups["@office"], []string{"203.0.113.0/24"}) {
+		t.Errorf("the previous members should be kept when the rules are not updated, got %v", groups)
+	}
+	tui.pages.RemovePage("message")
+
+	refused = false
+	save()
+	if groups, _ := tui.LoadGroups(); !reflect.DeepEqual(groups["@office"], []string{"192.0.2.9"}) {
+		t.Errorf("the members should be saved once the rules are updated, got %v", groups)
+	}
+}
+
+func TestLoadGroups_MissingFile(t *testing.T) {
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetGroupsFile(filepath.Join(t.TempDir(), "missing", "groups.conf"))
+
+	groups, err := tui.LoadGroups()
+	if err != nil || len(groups) != 0 {
+		t.Errorf("expected no group and no error, got %v, %v", groups, err)
+	}
+
+	if err := tui.SaveGroups(map[string][]string{"@vpn": {"10.8.0.0/24"}}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(filepath.Join(filepath.Dir(tui.groupsPath()), "groups.conf"))
+	if string(content) != "@vpn = 10.8.0.0/24\n" {
+		t.Errorf("unexpected groups file %q", content)
+	}
+}
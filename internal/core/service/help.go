@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var paneTitles = map[string]string{
+	PaneMenu:      "Menu",
+	PaneTable:     "Status table",
+	PaneListeners: "Listening services",
+	PaneHosts:     "Hosts",
+	PaneForm:      "Forms",
+	PaneDiff:      "Rule comparison",
+}
+
+// keysTable lists the actions of a pane along with their keys, as bound in
+// the registry.
+func (t *Tui) keysTable(pane string) *tview.Table {
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false).SetSelectedStyle(t.selectedStyle())
+	table.SetCell(0, 0, tview.NewTableCell("Keys").SetTextColor(t.theme.Header).SetSelectable(false))
+	table.SetCell(0, 1, tview.NewTableCell("Action").SetTextColor(t.theme.Header).SetSelectable(false).SetExpansion(1))
+	for r, def := range t.registry.Actions(pane) {
+		keys := strings.Join(def.Keys, ", ")
+		color := t.theme.Accent
+		if keys == "" {
+			keys, color = "unbound", t.theme.Text
+		}
+		table.SetCell(r+1, 0, tview.NewTableCell(keys).SetTextColor(color))
+		table.SetCell(r+1, 1, tview.NewTableCell(def.Description).SetTextColor(t.theme.Text).SetExpansion(1))
+	}
+	table.SetBorder(true).SetTitle(" Keys: "+paneTitles[pane]+" ").SetBorderPadding(0, 0, 1, 1)
+	return table
+}
+
+// KeysOverlay shows the keys of a pane over the layout. <Esc> or <?> closes it.
+func (t *Tui) KeysOverlay(pane string) {
+	focused := t.app.GetFocus()
+	table := t.keysTable(pane)
+
+	closeOverlay := func() {
+		t.pages.RemovePage("keys")
+		t.app.SetFocus(focused)
+	}
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			closeOverlay()
+		}
+	})
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == '?' {
+			closeOverlay()
+			return nil
+		}
+		return event
+	})
+
+	grid := tview.NewGrid().
+		SetColumns(0, 60, 0).
+		SetRows(0, min(table.GetRowCount()+2, 24), 0).
+		AddItem(table, 1, 1, 1, 1, 0, 0, true)
+
+	t.pages.AddPage("keys", grid, true, true)
+	t.app.SetFocus(table)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+// Host is a firewall tufw can manage, reached through its transport.
+type Host struct {
+	Name      string `yaml:"name"`
+	Transport string `yaml:"transport"`
+}
+
+// hostList returns the hosts of the configuration followed by those of
+// ~/.ssh/config when asked for. The local host comes first so it can be
+// switched back to.
+func hostList(config Config) []Host {
+	hosts := slices.Clone(config.Hosts)
+	if config.SSHConfig {
+		if home, err := os.UserHomeDir(); err == nil {
+			content, err := os.ReadFile(filepath.Join(home, ".ssh", "config"))
+			if err != nil {
+				log.Printf("ssh hosts unavailable: %v", err)
+			}
+			for _, alias := range utils.ParseSSHConfig(string(content)) {
+				if !slices.ContainsFunc(hosts, func(h Host) bool { return h.Name == alias }) {
+					hosts = append(hosts, Host{Name: alias, Transport: "ssh " + alias})
+				}
+			}
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil
+	}
+	if !slices.ContainsFunc(hosts, func(h Host) bool { return strings.TrimSpace(h.Transport) == "" }) {
+		hosts = append([]Host{{Name: "local"}}, hosts...)
+	}
+	return hosts
+}
+
+// CreateHosts fills the sidebar listing the hosts. Selecting one manages its
+// firewall instead, its back action goes back to the menu.
+func (t *Tui) CreateHosts() *tview.List {
+	t.hostsList.Clear()
+	for _, host := range t.hosts {
+		t.hostsList.AddItem(host.Name, "", 0, func() {
+			t.SwitchHost(host)
+		})
+	}
+	t.drawHosts()
+
+	t.registry.On(PaneHosts, "down", func() {
+		t.hostsList.SetCurrentItem((t.hostsList.GetCurrentItem() + 1) % t.hostsList.GetItemCount())
+	})
+	t.registry.On(PaneHosts, "up", func() {
+		t.hostsList.SetCurrentItem((t.hostsList.GetCurrentItem() + t.hostsList.GetItemCount() - 1) % t.hostsList.GetItemCount())
+	})
+	t.registry.On(PaneHosts, "back", func() { t.app.SetFocus(t.menu) })
+	t.registry.On(PaneHosts, "help", func() { t.KeysOverlay(PaneHosts) })
+	t.hostsList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return t.registry.Handle(PaneHosts, event)
+	})
+	t.hostsList.SetSecondaryTextColor(t.theme.Text).SetSelectedStyle(t.selectedStyle()).SetBorderPadding(1, 0, 1, 1)
+	t.hostsList.SetBorder(true).SetTitle(" Hosts ")
+	return t.hostsList
+}
+
+// drawHosts marks the host being managed.
+func (t *Tui) drawHosts() {
+	for i, host := range t.hosts {
+		label := "  " + host.Name
+		if t.isCurrentHost(host) {
+			label = "● " + host.Name
+			t.hostsList.SetCurrentItem(i)
+		}
+		t.hostsList.SetItemText(i, label, t.hostTransport(host).String())
+	}
+}
+
+func (t *Tui) hostTransport(host Host) utils.Transport {
+	transport, _ := utils.ParseTransport(host.Transport)
+	return transport
+}
+
+func (t *Tui) isCurrentHost(host Host) bool {
+	return t.hostTransport(host) == t.transport
+}
+
+// currentHost returns the name of the host being managed, empty when it is
+// not one of the hosts listed.
+func (t *Tui) currentHost() string {
+	for _, host := range t.hosts {
+		if t.isCurrentHost(host) {
+			return host.Name
+		}
+	}
+	return ""
+}
+
+// SwitchHost reloads the rules from host, where every operation then
+// applies. The host is left alone when ufw cannot be reached there, and
+// changes waiting to be confirmed have to be settled first as they would be
+// reverted on the previous host.
+func (t *Tui) SwitchHost(host Host) {
+	back := func() { t.app.SetFocus(t.hostsList) }
+	if t.isCurrentHost(host) {
+		t.app.SetFocus(t.menu)
+		return
+	}
+	if t.snapshot != "" {
+		t.CreateMessage(fmt.Sprintf("Confirm or revert the changes made on %s before switching host.", t.transport), back)
+		return
+	}
+
+	previous := t.transport
+	t.SetTransport(t.hostTransport(host))
+	if _, stderr, err := shellout("ufw status"); err != nil {
+		t.SetTransport(previous)
+		t.CreateMessage(tview.Escape(hostErrorMessage(host, stderr, err)), back)
+		return
+	}
+
+	t.session = t.sshSession()
+	t.LoadServices()
+	t.ClearMarks()
+	t.drawHosts()
+	t.ReloadTable()
+	t.table.ScrollToBeginning()
+	t.app.SetFocus(t.menu)
+}
+
+func hostErrorMessage(host Host, stderr string, err error) string {
+	reason := strings.TrimSpace(stderr)
+	if reason == "" {
+		reason = err.Error()
+	}
+	return fmt.Sprintf("Cannot manage the firewall of %s:\n\n%s", host.Name, reason)
+}
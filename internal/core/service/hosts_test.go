@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestHostList(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0700)
+	os.WriteFile(filepath.Join(home, ".ssh", "config"), []byte("Host fw1 fw2\n  User admin\nHost *\n  ForwardAgent no\n"), 0600)
+
+	config := DefaultConfig()
+	if hosts := hostList(config); hosts != nil {
+		t.Errorf("no host should be listed unless configured, got %v", hosts)
+	}
+
+	config.Hosts = []Host{{Name: "fw1", Transport: "ssh admin@fw1"}, {Name: "gateway", Transport: "docker exec gateway"}}
+	config.SSHConfig = true
+	expected := []Host{
+		{Name: "local"},
+		{Name: "fw1", Transport: "ssh admin@fw1"},
+		{Name: "gateway", Transport: "docker exec gateway"},
+		{Name: "fw2", Transport: "ssh fw2"},
+	}
+	if hosts := hostList(config); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("got %v, want %v", hosts, expected)
+	}
+
+	config.Hosts = []Host{{Name: "fw1"}, {Name: "fw1", Transport: "ssh"}}
+	var messages []string
+	for _, err := range config.Validate() {
+		messages = append(messages, err.Error())
+	}
+	if text := strings.Join(messages, "\n"); !strings.Contains(text, `"fw1" is listed twice`) || !strings.Contains(text, "fw1: ssh needs a host") {
+		t.Errorf("unexpected errors %q", text)
+	}
+}
+
+func TestSwitchHost(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	tui := CreateApplication(tcell.ColorBlue)
+	rules := map[string]string{
+		"":           "[ 1] 22/tcp ALLOW IN Anywhere",
+		"ssh fw1":    "[ 1] 80/tcp ALLOW IN Anywhere\n[ 2] 443/tcp ALLOW IN Anywhere",
+		"ssh broken": "",
+	}
+	shellout = func(cmd string) (string, string, error) {
+		switch {
+		case tui.transport.Prefix == "ssh broken" && cmd == "ufw status":
+			return "", "ssh: connect to host broken port 22: Connection refused", errors.New("exit status 255")
+		case strings.HasPrefix(cmd, "ufw status numbered"):
+			return rules[tui.transport.Prefix], "", nil
+		}
+		return "", "", nil
+	}
+
+	config := DefaultConfig()
+	config.Hosts = []Host{{Name: "fw1", Transport: "ssh fw1"}, {Name: "broken", Transport: "ssh broken"}}
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+	tui.ReloadTable()
+
+	if title := tui.titleBar.GetText(true); title != " tufw · local" {
+		t.Errorf("unexpected title %q", title)
+	}
+
+	tui.SwitchHost(tui.hosts[1])
+	if tui.transport.Prefix != "ssh fw1" || tui.ruleCount != 2 {
+		t.Errorf("the rules of fw1 should be loaded, got %d through %q", tui.ruleCount, tui.transport)
+	}
+	if title := tui.titleBar.GetText(true); title != " tufw · fw1 (ssh fw1)" {
+		t.Errorf("unexpected title %q", title)
+	}
+	if main, _ := tui.hostsList.GetItemText(1); main != "● fw1" {
+		t.Errorf("fw1 should be marked as the current host, got %q", main)
+	}
+
+	tui.SwitchHost(tui.hosts[2])
+	if name, _ := tui.pages.GetFrontPage(); name != "message" {
+		t.Errorf("an unreachable host should be reported")
+	}
+	if tui.transport.Prefix != "ssh fw1" || tui.ruleCount != 2 {
+		t.Errorf("an unreachable host should not be switched to")
+	}
+	tui.pages.RemovePage("message")
+
+	tui.snapshot = "/tmp/tufw-snapshot"
+	tui.SwitchHost(tui.hosts[0])
+	if !tui.transport.Remote() {
+		t.Errorf("changes waiting to be confirmed should prevent switching host")
+	}
+	tui.snapshot = ""
+
+	tui.SwitchHost(tui.hosts[0])
+	if !tui.transport.Local() || tui.ruleCount != 1 {
+		t.Errorf("the local rules should be loaded back")
+	}
+}
+
+func TestTitleBar_Escaped(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) { return "", "", nil }
+
+	config := DefaultConfig()
+	config.Hosts = []Host{{Name: "[v6]", Transport: "ssh [fe80::1]"}}
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+
+	tui.SwitchHost(tui.hosts[1])
+	if title := tui.titleBar.GetText(true); title != " tufw · [v6] (ssh [fe80::1])" {
+		t.Errorf("unexpected title %q", title)
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Panes the actions belong to, their keys being handled while it has focus.
+const (
+	PaneMenu      = "menu"
+	PaneTable     = "table"
+	PaneListeners = "listeners"
+	PaneHosts     = "hosts"
+	PaneForm      = "form"
+	PaneDiff      = "diff"
+)
+
+// ActionDef describes an action which can be bound to keys.
+type ActionDef struct {
+	Pane        string
+	Name        string
+	Description string
+	Keys        []string
+}
+
+// actionDefs lists every action with its default keys, in the order the
+// help shows them. An action name may be used in several panes, binding it
+// binds them all.
+var actionDefs = []ActionDef{
+	{PaneMenu, "search", "Search a rule", []string{"/"}},
+	{PaneMenu, "affects", "Which rules affect...", []string{"w"}},
+	{PaneMenu, "templates", "Rule templates", []string{"p"}},
+	{PaneMenu, "groups", "Address groups", []string{"g"}},
+	{PaneMenu, "test", "Test traffic", []string{"t"}},
+	{PaneMenu, "listeners", "Listening services", []string{"l"}},
+	{PaneMenu, "hosts", "Switch host", []string{"h"}},
+	{PaneMenu, "compare", "Compare rules", []string{"c"}},
+	{PaneMenu, "add", "Add a rule", []string{"a"}},
+	{PaneMenu, "edit", "Edit a rule", []string{"e"}},
+	{PaneMenu, "delete", "Delete a rule", []string{"d"}},
+	{PaneMenu, "filter", "Filter rules", []string{"f"}},
+	{PaneMenu, "analyze", "Analyze rules", []string{"z"}},
+	{PaneMenu, "disable", "Disable ufw", []string{"s"}},
+	{PaneMenu, "reset", "Reset rules", []string{"r"}},
+	{PaneMenu, "quit", "Exit", []string{"q"}},
+	{PaneMenu, "down", "Next entry", []string{"Down"}},
+	{PaneMenu, "up", "Previous entry", []string{"Up"}},
+	{PaneMenu, "help", "Show the keys", []string{"?"}},
+
+	{PaneTable, "down", "Next rule", []string{"Down"}},
+	{PaneTable, "up", "Previous rule", []string{"Up"}},
+	{PaneTable, "top", "First rule", []string{"Home"}},
+	{PaneTable, "bottom", "Last rule", []string{"End"}},
+	{PaneTable, "half-page-down", "Half a page down", nil},
+	{PaneTable, "half-page-up", "Half a page up", nil},
+	{PaneTable, "sort", "Sort by the nth column, again to reverse", []string{"1", "2", "3", "4", "5", "6", "7"}},
+	{PaneTable, "mark", "Mark the rule to delete", []string{"Space"}},
+	{PaneTable, "mark-range", "Mark a range of rules to delete", []string{"v"}},
+	{PaneTable, "back", "Back to the menu", []string{"Esc"}},
+	{PaneTable, "help", "Show the keys", []string{"?"}},
+
+	{PaneListeners, "allow", "Add a rule allowing the service", []string{"a"}},
+	{PaneListeners, "deny", "Add a rule denying the service", []string{"d"}},
+	{PaneListeners, "back", "Close the panel", []string{"Esc"}},
+	{PaneListeners, "help", "Show the keys", []string{"?"}},
+
+	{PaneHosts, "down", "Next host", []string{"Down"}},
+	{PaneHosts, "up", "Previous host", []string{"Up"}},
+	{PaneHosts, "back", "Back to the menu", []string{"Esc"}},
+	{PaneHosts, "help", "Show the keys", []string{"?"}},
+
+	{PaneDiff, "mark", "Mark the rule to copy", []string{"Space"}},
+	{PaneDiff, "copy-left", "Copy to the host being managed", []string{"<"}},
+	{PaneDiff, "copy-right", "Copy to the host compared with", []string{">"}},
+	{PaneDiff, "back", "Close the comparison", []string{"Esc"}},
+	{PaneDiff, "help", "Show the keys", []string{"?"}},
+
+	// Forms take any character, their keys are not printable ones
+	{PaneForm, "form-help", "Show the keys", []string{"F1"}},
+}
+
+// Keymaps are presets replacing the default keys of the actions they name.
+var Keymaps = map[string]map[string][]string{
+	"default": {},
+	"vim": {
+		"down":           {"Down", "j"},
+		"up":             {"Up", "k"},
+		"top":            {"Home", "g g"},
+		"bottom":         {"End", "G"},
+		"half-page-down": {"Ctrl+D"},
+		"half-page-up":   {"Ctrl+U"},
+	},
+}
+
+// Keys are the keys bound to an action. In YAML it is either a single key
+// or a list of them.
+type Keys []string
+
+func (k *Keys) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = Keys{value.Value}
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// keyStroke is a single key press.
+type keyStroke struct {
+	key tcell.Key
+	ch  rune
+}
+
+func strokeOf(event *tcell.EventKey) keyStroke {
+	if event.Key() == tcell.KeyRune {
+		return keyStroke{key: tcell.KeyRune, ch: event.Rune()}
+	}
+	return keyStroke{key: event.Key()}
+}
+
+// parseStroke reads a key such as "a", "Space", "Esc" or "Ctrl+D".
+func parseStroke(name string) (keyStroke, error) {
+	if r, size := utf8.DecodeRuneInString(name); size > 0 && size == len(name) {
+		return keyStroke{key: tcell.KeyRune, ch: r}, nil
+	}
+	if strings.EqualFold(name, "Space") {
+		return keyStroke{key: tcell.KeyRune, ch: ' '}, nil
+	}
+
+	normalized := strings.ReplaceAll(name, "+", "-")
+	for key, keyName := range tcell.KeyNames {
+		if strings.EqualFold(keyName, normalized) {
+			return keyStroke{key: key}, nil
+		}
+	}
+	return keyStroke{}, fmt.Errorf("unknown key %q", name)
+}
+
+// parseKeys reads a key sequence, its keys being separated by spaces like "g g".
+func parseKeys(spec string) ([]keyStroke, error) {
+	var strokes []keyStroke
+	for _, name := range strings.Fields(spec) {
+		stroke, err := parseStroke(name)
+		if err != nil {
+			return nil, err
+		}
+		strokes = append(strokes, stroke)
+	}
+	if len(strokes) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	return strokes, nil
+}
+
+// Bindings returns the keys of every action for the keymap, with keys
+// replacing those of the actions they name.
+func Bindings(keymap string, keys map[string]Keys) map[string][]string {
+	bindings := map[string][]string{}
+	for _, def := range actionDefs {
+		bindings[def.Name] = def.Keys
+	}
+	for name, preset := range Keymaps[keymap] {
+		bindings[name] = preset
+	}
+	for name, custom := range keys {
+		bindings[name] = custom
+	}
+	return bindings
+}
+
+// ValidateBindings reports unknown actions and keys, and keys which are
+// ambiguous in a pane.
+func ValidateBindings(bindings map[string][]string) []error {
+	var errs []error
+
+	known := map[string]bool{}
+	for _, def := range actionDefs {
+		known[def.Name] = true
+	}
+	type bound struct {
+		name    string
+		spec    string
+		strokes []keyStroke
+	}
+	parsed := map[string][]bound{}
+	for name, keys := range bindings {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("unknown action %q", name))
+			continue
+		}
+		for _, spec := range keys {
+			strokes, err := parseKeys(spec)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			parsed[name] = append(parsed[name], bound{name, spec, strokes})
+		}
+	}
+
+	panes := map[string][]bound{}
+	for _, def := range actionDefs {
+		panes[def.Pane] = append(panes[def.Pane], parsed[def.Name]...)
+	}
+	for pane, keys := range panes {
+		for i, a := range keys {
+			for _, b := range keys[i+1:] {
+				if a.name != b.name && (hasPrefix(a.strokes, b.strokes) || hasPrefix(b.strokes, a.strokes)) {
+					errs = append(errs, fmt.Errorf("%q of %s conflicts with %q of %s in the %s", a.spec, a.name, b.spec, b.name, pane))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func hasPrefix(strokes []keyStroke, prefix []keyStroke) bool {
+	return len(prefix) <= len(strokes) && slices.Equal(strokes[:len(prefix)], prefix)
+}
+
+// Registry dispatches key presses to the handlers of the actions bound to them.
+type Registry struct {
+	bindings map[string][][]keyStroke
+	specs    map[string][]string
+	handlers map[string]func(int)
+	pending  []keyStroke
+}
+
+// NewRegistry creates a registry for validated bindings.
+func NewRegistry(bindings map[string][]string) *Registry {
+	r := &Registry{
+		bindings: map[string][][]keyStroke{},
+		specs:    bindings,
+		handlers: map[string]func(int){},
+	}
+	for name, keys := range bindings {
+		for _, spec := range keys {
+			if strokes, err := parseKeys(spec); err == nil {
+				r.bindings[name] = append(r.bindings[name], strokes)
+			}
+		}
+	}
+	return r
+}
+
+func handlerKey(pane, name string) string {
+	return pane + "/" + name
+}
+
+// On sets the handler of an action of a pane.
+func (r *Registry) On(pane, name string, handler func()) {
+	r.handlers[handlerKey(pane, name)] = func(int) { handler() }
+}
+
+// OnNth sets the handler of an action, called with the index of the key
+// pressed among the keys bound to it.
+func (r *Registry) OnNth(pane, name string, handler func(int)) {
+	r.handlers[handlerKey(pane, name)] = handler
+}
+
+// Keys returns the keys bound to an action.
+func (r *Registry) Keys(name string) []string {
+	return r.specs[name]
+}
+
+// Label returns the first key bound to an action, to mention it in help texts.
+func (r *Registry) Label(name string) string {
+	if keys := r.specs[name]; len(keys) > 0 {
+		return keys[0]
+	}
+	return "unbound"
+}
+
+// Shortcut returns the key of an action when it is a single character.
+func (r *Registry) Shortcut(name string) rune {
+	for _, strokes := range r.bindings[name] {
+		if len(strokes) == 1 && strokes[0].key == tcell.KeyRune && strokes[0].ch != ' ' {
+			return strokes[0].ch
+		}
+	}
+	return 0
+}
+
+// Actions returns the actions of a pane which have a handler, with the keys
+// currently bound to them.
+func (r *Registry) Actions(pane string) []ActionDef {
+	var defs []ActionDef
+	for _, def := range actionDefs {
+		if _, ok := r.handlers[handlerKey(pane, def.Name)]; def.Pane == pane && ok {
+			def.Keys = r.specs[def.Name]
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// Handle runs the action of the pane bound to the keys pressed, returning
+// nil once the event is consumed, including by the start of a key sequence.
+func (r *Registry) Handle(pane string, event *tcell.EventKey) *tcell.EventKey {
+	sequence := append(r.pending, strokeOf(event))
+	r.pending = nil
+
+	partial := false
+	for _, def := range actionDefs {
+		handler, ok := r.handlers[handlerKey(pane, def.Name)]
+		if def.Pane != pane || !ok {
+			continue
+		}
+		for i, strokes := range r.bindings[def.Name] {
+			switch {
+			case slices.Equal(strokes, sequence):
+				handler(i)
+				return nil
+			case hasPrefix(strokes, sequence):
+				partial = true
+			}
+		}
+	}
+
+	if partial {
+		r.pending = sequence
+		return nil
+	}
+	// An unfinished sequence does not swallow the key which interrupted it
+	if len(sequence) > 1 {
+		return r.Handle(pane, event)
+	}
+	return event
+}
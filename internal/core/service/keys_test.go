@@ -0,0 +1,248 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+func runeKey(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []keyStroke
+	}{
+		{"a", []keyStroke{{tcell.KeyRune, 'a'}}},
+		{"Space", []keyStroke{{tcell.KeyRune, ' '}}},
+		{"Ctrl+D", []keyStroke{{tcell.KeyCtrlD, 0}}},
+		{"ctrl-u", []keyStroke{{tcell.KeyCtrlU, 0}}},
+		{"Esc", []keyStroke{{tcell.KeyEscape, 0}}},
+		{"g g", []keyStroke{{tcell.KeyRune, 'g'}, {tcell.KeyRune, 'g'}}},
+	}
+
+	for _, tt := range tests {
+		strokes, err := parseKeys(tt.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(strokes, tt.expected) {
+			t.Errorf("%q: got %v, want %v", tt.spec, strokes, tt.expected)
+		}
+	}
+
+	for _, spec := range []string{"", "Hyper+X", "ab"} {
+		if _, err := parseKeys(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestKeysYAML(t *testing.T) {
+	var keys map[string]Keys
+	if err := yaml.Unmarshal([]byte("add: n\ndown: [j, Down]\n"), &keys); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]Keys{"add": {"n"}, "down": {"j", "Down"}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("got %v, want %v", keys, expected)
+	}
+}
+
+func TestValidateBindings(t *testing.T) {
+	for name := range Keymaps {
+		if errs := ValidateBindings(Bindings(name, nil)); len(errs) > 0 {
+			t.Errorf("keymap %s: %v", name, errs)
+		}
+	}
+
+	// g is the Address groups entry of the menu but only starts gg in the table
+	if errs := ValidateBindings(Bindings("vim", map[string]Keys{"mark": {"g"}})); len(errs) != 1 ||
+		!strings.Contains(errs[0].Error(), `"g g" of top conflicts with "g" of mark in the table`) {
+		t.Errorf("expected a conflict between g and gg, got %v", errs)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(Bindings("vim", map[string]Keys{"quit": {"Ctrl+Q", "q"}}))
+
+	var calls []string
+	r.On(PaneTable, "top", func() { calls = append(calls, "top") })
+	r.On(PaneTable, "down", func() { calls = append(calls, "down") })
+	r.On(PaneMenu, "down", func() { calls = append(calls, "menu down") })
+	r.On(PaneMenu, "quit", func() { calls = append(calls, "quit") })
+	r.OnNth(PaneTable, "sort", func(n int) { calls = append(calls, "sort "+string(rune('1'+n))) })
+
+	events := []struct {
+		pane     string
+		event    *tcell.EventKey
+		consumed bool
+	}{
+		{PaneTable, runeKey('j'), true},
+		{PaneTable, runeKey('g'), true},
+		{PaneTable, runeKey('g'), true},
+		{PaneTable, runeKey('3'), true},
+		{PaneTable, runeKey('g'), true},
+		{PaneTable, runeKey('j'), true},
+		{PaneTable, runeKey('x'), false},
+		{PaneMenu, runeKey('j'), true},
+		{PaneMenu, tcell.NewEventKey(tcell.KeyCtrlQ, 0, tcell.ModCtrl), true},
+		{PaneMenu, runeKey('q'), true},
+		{PaneMenu, runeKey('G'), false},
+	}
+	for i, e := range events {
+		if consumed := r.Handle(e.pane, e.event) == nil; consumed != e.consumed {
+			t.Errorf("event %d: consumed %v, want %v", i, consumed, e.consumed)
+		}
+	}
+
+	expected := []string{"down", "top", "sort 3", "down", "menu down", "quit", "quit"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("got %v, want %v", calls, expected)
+	}
+
+	if r.Shortcut("quit") != 'q' || r.Shortcut("top") != 0 || r.Label("half-page-up") != "Ctrl+U" {
+		t.Errorf("unexpected shortcuts")
+	}
+}
+
+func TestTableNavigation(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return strings.Join(statusRows, "\n"), "", nil
+	}
+
+	config := DefaultConfig()
+	config.Keymap = "vim"
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.ReloadTable()
+	tui.table.SetSelectable(true, false)
+
+	keys := []struct {
+		event *tcell.EventKey
+		row   int
+	}{
+		{runeKey('j'), 2},
+		{runeKey('j'), 3},
+		{runeKey('k'), 2},
+		{runeKey('G'), len(statusRows)},
+		{runeKey('j'), len(statusRows)},
+		{runeKey('g'), len(statusRows)},
+		{runeKey('g'), 1},
+		{tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModNone), len(statusRows)},
+	}
+	for i, k := range keys {
+		tui.tableInput(k.event)
+		if row, _ := tui.table.GetSelection(); row != k.row {
+			t.Errorf("key %d: selected row %d, want %d", i, row, k.row)
+		}
+	}
+}
+
+func TestKeysOverlay(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) { return "", "", nil }
+
+	config := DefaultConfig()
+	config.Keys = map[string]Keys{"add": {"n"}}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+	tui.CreateMenu()
+
+	tui.registry.Handle(PaneMenu, runeKey('?'))
+	if !tui.pages.HasPage("keys") {
+		t.Fatalf("expected the keys overlay to be shown")
+	}
+
+	overlay, ok := tui.app.GetFocus().(*tview.Table)
+	if !ok {
+		t.Fatalf("expected the keys overlay to be focused")
+	}
+	overlay.GetInputCapture()(runeKey('?'))
+	if tui.pages.HasPage("keys") {
+		t.Errorf("the keys overlay should close on ?")
+	}
+
+	table := tui.keysTable(PaneMenu)
+	listed := map[string]string{}
+	for row := 1; row < table.GetRowCount(); row++ {
+		listed[table.GetCell(row, 1).Text] = table.GetCell(row, 0).Text
+	}
+	if listed["Add a rule"] != "n" || listed["Show the keys"] != "?" {
+		t.Errorf("unexpected keys %v", listed)
+	}
+	if _, ok := listed["Next rule"]; ok {
+		t.Errorf("the keys of the table should not be listed for the menu")
+	}
+
+	// Unbound actions are listed too
+	listed = map[string]string{}
+	table = tui.keysTable(PaneTable)
+	for row := 1; row < table.GetRowCount(); row++ {
+		listed[table.GetCell(row, 1).Text] = table.GetCell(row, 0).Text
+	}
+	if listed["Half a page down"] != "unbound" || listed["Sort by the nth column, again to reverse"] != "1, 2, 3, 4, 5, 6, 7" {
+		t.Errorf("unexpected keys %v", listed)
+	}
+}
+
+func TestPaneKeys(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) { return "", "", nil }
+
+	config := DefaultConfig()
+	config.Hosts = []Host{{Name: "fw1", Transport: "ssh fw1"}}
+	config.Keymap = "vim"
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+	tui.CreateMenu()
+
+	for _, tt := range []struct {
+		pane  string
+		input func(*tcell.EventKey) *tcell.EventKey
+		key   *tcell.EventKey
+	}{
+		{PaneHosts, tui.hostsList.GetInputCapture(), runeKey('?')},
+		{PaneForm, tui.form.GetInputCapture(), tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModNone)},
+	} {
+		tt.input(tt.key)
+		if name, _ := tui.pages.GetFrontPage(); name != "keys" {
+			t.Errorf("%s: expected the keys overlay to be shown", tt.pane)
+		}
+		tui.pages.RemovePage("keys")
+	}
+
+	// Characters are typed in the forms
+	if event := tui.form.GetInputCapture()(runeKey('?')); event == nil {
+		t.Errorf("? should be left to the form fields")
+	}
+
+	tui.app.SetFocus(tui.hostsList)
+	tui.hostsList.GetInputCapture()(runeKey('j'))
+	if tui.hostsList.GetCurrentItem() != 1 {
+		t.Errorf("j should select the next host with the vim keymap")
+	}
+	tui.hostsList.GetInputCapture()(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone))
+	if tui.app.GetFocus() != tui.menu.GetItem(0) {
+		t.Errorf("Esc should go back to the menu")
+	}
+}
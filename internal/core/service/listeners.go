@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+var listenerColumns = []string{"Proto", "Address", "Port", "Process", "Reachable from"}
+
+// LoadListeners returns the listening sockets from ss, falling back to
+// /proc/net when it is not installed.
+func (t *Tui) LoadListeners() []domain.Listener {
+	out, _, err := shellout("ss -tulpnH")
+	if err == nil {
+		if listeners := utils.ParseSS(out); len(listeners) > 0 {
+			return listeners
+		}
+	}
+	log.Printf("ss unavailable, reading /proc/net instead")
+
+	var listeners []domain.Listener
+	for _, file := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		out, _, err := shellout("cat /proc/net/" + file)
+		if err != nil {
+			continue
+		}
+		listeners = append(listeners, utils.ParseProcNet(out, file[:3])...)
+	}
+	return listeners
+}
+
+func (t *Tui) exposureColor(exposure string) tcell.Color {
+	switch exposure {
+	case utils.ExposureAnywhere:
+		return t.theme.Error
+	case utils.ExposureSome:
+		return t.theme.Warning
+	case utils.ExposureNone:
+		return t.theme.Success
+	}
+	return t.theme.Text
+}
+
+// listenerFormValues pre-fills a rule allowing or denying the listener from anywhere.
+func listenerFormValues(l domain.Listener, action string) domain.FormValues {
+	fv := domain.FormValues{Port: l.Port, Protocol: l.Protocol, Action: action, Comment: l.Process}
+	if l.Address != "any" {
+		fv.To = l.Address
+	}
+	return fv
+}
+
+// fillForm sets the fields of the rule form to the given values.
+func (t *Tui) fillForm(fv domain.FormValues) {
+	inputs := map[string]string{"To": fv.To, "Port": fv.Port, "From": fv.From, "Comment": fv.Comment}
+	for label, value := range inputs {
+		if field, ok := t.form.GetFormItemByLabel(label).(*tview.InputField); ok {
+			field.SetText(value)
+		}
+	}
+
+	dropDowns := map[string][]string{"Action *": actions, "Protocol": {"", "tcp", "udp"}}
+	values := map[string]string{"Action *": fv.Action, "Protocol": fv.Protocol}
+	for label, options := range dropDowns {
+		dropDown, ok := t.form.GetFormItemByLabel(label).(*tview.DropDown)
+		if !ok {
+			continue
+		}
+		for i, option := range options {
+			if option == values[label] {
+				dropDown.SetCurrentOption(i)
+			}
+		}
+	}
+}
+
+// ListenersPanel lists the listening sockets and from where the rules let
+// them be reached, with shortcuts to allow or deny them.
+func (t *Tui) ListenersPanel() {
+	listeners := t.LoadListeners()
+	sort.SliceStable(listeners, func(i, j int) bool {
+		pi, _ := strconv.Atoi(listeners[i].Port)
+		pj, _ := strconv.Atoi(listeners[j].Port)
+		if pi != pj {
+			return pi < pj
+		}
+		return listeners[i].Protocol < listeners[j].Protocol
+	})
+
+	rules := t.resolveApps(t.LoadRules())
+	defaults := t.LoadDefaults()
+
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false).SetSelectedStyle(t.selectedStyle())
+	for c, column := range listenerColumns {
+		table.SetCell(0, c, tview.NewTableCell(column).SetTextColor(t.theme.Header).SetAlign(tview.AlignCenter).SetSelectable(false))
+	}
+	for r, l := range listeners {
+		address := l.Address
+		if l.V6 {
+			address += " (v6)"
+		}
+		if l.Interface != "" {
+			address += " on " + l.Interface
+		}
+		exposure := utils.Exposure(rules, defaults, l)
+
+		for c, text := range []string{l.Protocol, address, l.Port, l.Process, exposure} {
+			color := t.theme.Text
+			if c == 4 {
+				color = t.exposureColor(exposure)
+			}
+			table.SetCell(r+1, c, tview.NewTableCell(text).SetTextColor(color).SetAlign(tview.AlignCenter).SetExpansion(1))
+		}
+	}
+	table.SetBorder(true).SetTitle(fmt.Sprintf(" Listening services (%d) ", len(listeners)))
+
+	closePanel := func() {
+		t.pages.RemovePage("services")
+		t.app.SetFocus(t.menu)
+	}
+	prefill := func(action string) {
+		row, _ := table.GetSelection()
+		if row < 1 || row > len(listeners) {
+			return
+		}
+		t.pages.RemovePage("services")
+		t.Reset()
+		t.CreateForm()
+		t.fillForm(listenerFormValues(listeners[row-1], action))
+		t.app.SetFocus(t.form)
+	}
+
+	t.registry.On(PaneListeners, "allow", func() { prefill("ALLOW IN") })
+	t.registry.On(PaneListeners, "deny", func() { prefill("DENY IN") })
+	t.registry.On(PaneListeners, "back", closePanel)
+	t.registry.On(PaneListeners, "help", func() { t.KeysOverlay(PaneListeners) })
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return t.registry.Handle(PaneListeners, event)
+	})
+
+	help := tview.NewTextView().SetText(fmt.Sprintf("<%s> Allow  <%s> Deny  <%s> Close  <%s> Keys",
+		t.registry.Label("allow"), t.registry.Label("deny"), t.registry.Label("back"), t.registry.Label("help"))).SetTextColor(t.theme.Accent).SetTextAlign(tview.AlignCenter)
+	panel := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(help, 1, 0, false)
+
+	grid := tview.NewGrid().
+		SetColumns(0, 100, 0).
+		SetRows(0, 24, 0).
+		AddItem(panel, 1, 1, 1, 1, 0, 0, true)
+
+	t.pages.AddPage("services", grid, true, true)
+	t.app.SetFocus(table)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/rivo/tview"
+)
+
+func TestLoadListeners_FallsBackToProc(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		switch cmd {
+		case "cat /proc/net/tcp":
+			return "  sl  local_address rem_address   st\n   0: 00000000:0016 00000000:0000 0A\n", "", nil
+		case "cat /proc/net/udp6":
+			return "  sl  local_address remote_address st\n  10: 00000000000000000000000000000000:14E9 00000000000000000000000000000000:0000 07\n", "", nil
+		}
+		return "", "bash: ss: command not found", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	expected := []domain.Listener{
+		{Protocol: "tcp", Address: "any", Port: "22"},
+		{Protocol: "udp", Address: "any", Port: "5353", V6: true},
+	}
+	if got := tui.LoadListeners(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, want %+v", got, expected)
+	}
+	if commands[0] != "ss -tulpnH" {
+		t.Errorf("expected ss to be tried first, got %q", commands[0])
+	}
+}
+
+func TestFillForm_FromListener(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ip link show") {
+			return "lo\neth0\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateForm()
+
+	listener := domain.Listener{Protocol: "tcp", Address: "192.168.1.10", Port: "5432", Process: "postgres"}
+	tui.fillForm(listenerFormValues(listener, "DENY IN"))
+
+	expected := domain.FormValues{To: "192.168.1.10", Port: "5432", Protocol: "tcp", Action: "deny-in", Comment: "postgres"}
+	if got := tui.ParseFormValues(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, want %+v", got, expected)
+	}
+}
+
+func TestListenersPanel_Keys(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		if cmd == "cat /proc/net/tcp" {
+			return "  sl  local_address rem_address   st\n   0: 00000000:0016 00000000:0000 0A\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	config := DefaultConfig()
+	config.Keys = map[string]Keys{"allow": {"x"}}
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Configure(config)
+	tui.Init()
+	tui.CreateLayout()
+	tui.ListenersPanel()
+
+	table, ok := tui.app.GetFocus().(*tview.Table)
+	if !ok {
+		t.Fatalf("expected the listening services to be focused")
+	}
+	table.Select(1, 0)
+	press := func(ch rune) {
+		table.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, ch, tcell.ModNone))
+	}
+
+	press('a')
+	if name, _ := tui.pages.GetFrontPage(); name != "services" {
+		t.Errorf("a should no longer add a rule once allow is bound to x")
+	}
+	press('?')
+	if name, _ := tui.pages.GetFrontPage(); name != "keys" {
+		t.Errorf("? should list the keys of the listening services")
+	}
+	tui.pages.RemovePage("keys")
+	press('x')
+	if port := tui.form.GetFormItemByLabel("Port"); port == nil || port.(*tview.InputField).GetText() != "22" {
+		t.Errorf("x should prefill the rule form with the service")
+	}
+}
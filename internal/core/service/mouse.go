@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+// tableMouse edits the rule of a row on a double click and opens its context
+// menu on a right click. Clicks and the scroll wheel are handled by the table.
+func (t *Tui) tableMouse(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+	if action != tview.MouseLeftDoubleClick && action != tview.MouseRightClick {
+		return action, event
+	}
+	x, y := event.Position()
+	row, _ := t.table.CellAt(x, y)
+	if row < 1 || row >= t.table.GetRowCount() || t.marked != nil {
+		return action, event
+	}
+
+	t.app.SetFocus(t.table)
+	t.table.Select(row, 0)
+	if action == tview.MouseLeftDoubleClick {
+		t.Reset()
+		t.editRow(row)
+	} else {
+		t.ContextMenu(row, x, y)
+	}
+	return tview.MouseConsumed, nil
+}
+
+// ContextMenu opens the menu of the actions on the rule of a row at the
+// given screen position. <Esc> or a click elsewhere closes it.
+func (t *Tui) ContextMenu(row int, x int, y int) {
+	number := t.RuleNumber(row)
+	list := tview.NewList().ShowSecondaryText(false)
+
+	closeMenu := func() {
+		t.pages.RemovePage("context")
+		t.pages.SetMouseCapture(nil)
+		t.app.SetFocus(t.table)
+	}
+	addItem := func(label string, selected func()) {
+		list.AddItem(label, "", 0, func() {
+			closeMenu()
+			selected()
+		})
+	}
+
+	addItem("Edit", func() {
+		t.Reset()
+		t.editRow(row)
+	})
+	addItem("Duplicate", func() {
+		t.Reset()
+		t.createForm(t.rowFormValues(row))
+		t.app.SetFocus(t.form)
+	})
+	addItem("Delete", func() { t.deleteRules([]int{number}) })
+	addItem("Move up", func() { t.MoveRule(number, -1) })
+	addItem("Move down", func() { t.MoveRule(number, 1) })
+
+	list.SetDoneFunc(closeMenu)
+	list.SetSelectedStyle(t.selectedStyle()).SetBorder(true).SetTitle(fmt.Sprintf(" Rule %d ", number))
+
+	// Keep the menu on screen
+	width, height := 16, list.GetItemCount()+2
+	_, _, screenWidth, screenHeight := t.pages.GetRect()
+	if screenWidth > 0 {
+		x = max(0, min(x, screenWidth-width))
+		y = max(0, min(y, screenHeight-height))
+	}
+	list.SetRect(x, y, width, height)
+
+	t.pages.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftDown || action == tview.MouseRightDown {
+			if !list.InRect(event.Position()) {
+				closeMenu()
+				return tview.MouseConsumed, nil
+			}
+		}
+		return action, event
+	})
+	t.pages.AddPage("context", list, false, true)
+	t.app.SetFocus(list)
+}
+
+// MoveRule swaps a rule with the one before or after it, depending on the
+// sign of offset. Rules only move among those of the same IP version.
+func (t *Tui) MoveRule(number int, offset int) {
+	rules := t.LoadRules()
+	index := -1
+	for i, rule := range rules {
+		if rule.Number == number {
+			index = i
+		}
+	}
+	target := index + offset
+	if index == -1 || target < 0 || target >= len(rules) || rules[target].V6 != rules[index].V6 {
+		t.secondHelp.SetText(fmt.Sprintf(" Rule %d cannot be moved further", number)).SetTextColor(t.theme.Error)
+		return
+	}
+
+	// Once the rule is deleted, inserting it at the number of its target
+	// places it right after it when moving down, and right before it otherwise.
+	// Past the last rule of its IP version, it is appended instead.
+	position := rules[target].Number
+	if target+1 == len(rules) || rules[target+1].V6 != rules[index].V6 {
+		if offset > 0 {
+			position = 0
+		}
+	}
+
+	// A rule added for both IP versions is listed twice, once as IPv6. Both
+	// are deleted as re-creating it adds both again, from the IPv4 one.
+	fv := utils.FormFromRule(rules[index])
+	remove := []int{number}
+	if len(utils.RuleFromForm(fv)) > 1 {
+		if rules[index].V6 {
+			t.secondHelp.SetText(fmt.Sprintf(" Rule %d applies to IPv4 too, move it from its IPv4 rule", number)).SetTextColor(t.theme.Error)
+			return
+		}
+		for _, rule := range rules {
+			if rule.V6 && utils.FormFromRule(rule) == fv {
+				remove = append(remove, rule.Number)
+				break
+			}
+		}
+	}
+	t.applyRules(remove, []domain.FormValues{fv}, []string{ruleCommand(fv, position)}, func() {})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/rivo/tview"
+)
+
+func TestMoveRule(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	rows := []string{
+		"[ 1] 22/tcp ALLOW IN Anywhere",
+		"[ 2] 80/tcp ALLOW IN 10.0.0.0/8",
+		"[ 3] 22/tcp (v6) ALLOW IN Anywhere (v6)",
+		"[ 4] 80/tcp (v6) ALLOW IN Anywhere (v6)",
+	}
+
+	tests := []struct {
+		number   int
+		offset   int
+		expected []string
+	}{
+		{2, -1, []string{"ufw --force delete 2", "ufw insert 1 allow in from 10.0.0.0/8 to any proto tcp port 80"}},
+		{1, 1, []string{"ufw --force delete 3", "ufw --force delete 1", "ufw allow in from any to any proto tcp port 22"}},
+		{1, -1, nil},
+		{2, 1, nil},
+		{3, -1, nil},
+		{4, -1, nil},
+	}
+
+	for _, tt := range tests {
+		var commands []string
+		shellout = func(cmd string) (string, string, error) {
+			if strings.HasPrefix(cmd, "ufw status numbered") {
+				return strings.Join(rows, "\n"), "", nil
+			}
+			if strings.HasPrefix(cmd, "ufw --force") || strings.HasPrefix(cmd, "ufw insert") || strings.HasPrefix(cmd, "ufw allow") {
+				commands = append(commands, cmd)
+			}
+			return "", "", nil
+		}
+
+		tui := CreateApplication(tcell.ColorBlue)
+		tui.Init()
+		tui.CreateLayout()
+		tui.MoveRule(tt.number, tt.offset)
+
+		if !reflect.DeepEqual(commands, tt.expected) {
+			t.Errorf("moving %d by %d: expected %q, got %q", tt.number, tt.offset, tt.expected, commands)
+		}
+		if tt.expected == nil && !strings.Contains(tui.secondHelp.GetText(true), fmt.Sprintf(" Rule %d ", tt.number)) {
+			t.Errorf("moving %d by %d should be refused", tt.number, tt.offset)
+		}
+	}
+}
+
+func TestTableMouse(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return strings.Join(statusRows, "\n"), "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateLayout()
+	tui.ReloadTable()
+	tui.table.SetRect(0, 0, 80, 10)
+
+	// The second rule is two lines below the header
+	_, top, _, _ := tui.table.GetInnerRect()
+	click := func(action tview.MouseAction) {
+		tui.tableMouse(action, tcell.NewEventMouse(5, top+2, tcell.ButtonNone, tcell.ModNone))
+	}
+
+	click(tview.MouseLeftDoubleClick)
+	expected := domain.FormValues{Port: "443", Protocol: "tcp", Action: "deny-in", From: "10.0.0.0/8"}
+	if values := tui.ParseFormValues(); values != expected {
+		t.Errorf("editing: got %+v, want %+v", values, expected)
+	}
+	tui.Reset()
+
+	click(tview.MouseRightClick)
+	_, page := tui.pages.GetFrontPage()
+	menu, ok := page.(*tview.List)
+	if !ok {
+		t.Fatalf("expected the context menu to be shown")
+	}
+
+	// Duplicate
+	menu.SetCurrentItem(1)
+	menu.InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), nil)
+	if tui.pages.HasPage("context") {
+		t.Errorf("the context menu should be closed once an entry is selected")
+	}
+	if values := tui.ParseFormValues(); values != expected {
+		t.Errorf("duplicating: got %+v, want %+v", values, expected)
+	}
+	if tui.form.GetButtonIndex("Save") == -1 || tui.form.GetFormItemByLabel("To").(*tview.InputField).GetText() != "" {
+		t.Errorf("unexpected duplicate form")
+	}
+
+	// Rules being marked are not edited
+	tui.Reset()
+	tui.marked = map[int]bool{}
+	click(tview.MouseRightClick)
+	if tui.pages.HasPage("context") {
+		t.Errorf("no context menu should open while marking rules")
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// keyRepeatDelay is how long a key is held down before the terminal repeats
+// it, at most. Until then the same key is taken as still held.
+const keyRepeatDelay = time.Second
+
+// revertScript restores the ufw rules and state saved in dir, then removes it.
+func revertScript(dir string) string {
+	return fmt.Sprintf("cp -p %[1]s/user.rules %[1]s/user6.rules /etc/ufw/ && "+
+		"if grep -q inactive %[1]s/status; then ufw --force disable; else ufw reload; fi; rm -rf %[1]s", dir)
+}
+
+// Snapshot saves the current rules and state of ufw so they can be restored later.
+func (t *Tui) Snapshot() (string, error) {
+	out, trace, err := shellout("mktemp -d /var/tmp/tufw-revert.XXXXXX")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %s", trace)
+	}
+	dir := strings.TrimSpace(out)
+
+	cmd := fmt.Sprintf("cp -p /etc/ufw/user.rules /etc/ufw/user6.rules %[1]s/ && ufw status | head -n 1 > %[1]s/status", dir)
+	if _, trace, err := shellout(cmd); err != nil {
+		shellout("rm -rf " + dir)
+		return "", fmt.Errorf("failed to snapshot rules: %s", trace)
+	}
+
+	return dir, nil
+}
+
+// SafeApply runs apply and, when a revert timer is set, schedules the
+// restoration of the rules as they were before, unless the user confirms
+// the changes in time. The restoration is done by a detached helper so it
+// happens even if tufw is killed or the session is lost.
+func (t *Tui) SafeApply(apply func()) {
+	if t.revertAfter == 0 {
+		apply()
+		return
+	}
+
+	// Consecutive changes are reverted all at once to the first snapshot
+	fresh := t.snapshot == ""
+	if fresh {
+		dir, err := t.Snapshot()
+		if err != nil {
+			log.Print(err)
+			t.CreateMessage(fmt.Sprintf("Changes not applied: %v", err), func() {})
+			return
+		}
+		t.snapshot = dir
+	}
+
+	// Nothing is applied unless it can be reverted
+	helper := fmt.Sprintf("setsid nohup bash -c 'sleep %d; [ -d %s ] && { %s; }' </dev/null >/dev/null 2>&1 & echo $!",
+		int(t.revertAfter.Seconds()), t.snapshot, revertScript(t.snapshot))
+	out, trace, err := shellout(helper)
+	pid := strings.TrimSpace(out)
+	if err != nil || pid == "" {
+		log.Printf("Failed to start revert helper: %s", trace)
+		if fresh {
+			shellout("rm -rf " + t.snapshot)
+			t.snapshot = ""
+		}
+		reason := strings.TrimSpace(trace)
+		if reason == "" && err != nil {
+			reason = err.Error()
+		}
+		t.CreateMessage(tview.Escape(fmt.Sprintf("Changes not applied: failed to start the revert helper: %s", reason)), func() {})
+		return
+	}
+	if !fresh {
+		t.stopRevertHelper()
+	}
+	t.revertPid = pid
+	log.Printf("Changes will be reverted in %s unless confirmed (helper %s)", t.revertAfter, t.revertPid)
+
+	apply()
+
+	// Let the caller settle the focus first so the countdown ends up on top
+	go t.app.QueueUpdateDraw(t.CreateRevertCountdown)
+}
+
+func (t *Tui) stopRevertHelper() {
+	if t.revertPid != "" {
+		shellout(fmt.Sprintf("kill %s 2>/dev/null", t.revertPid))
+		t.revertPid = ""
+	}
+	if t.revertStop != nil {
+		close(t.revertStop)
+		t.revertStop = nil
+	}
+}
+
+// ConfirmChanges keeps the applied changes and cancels the pending revert.
+func (t *Tui) ConfirmChanges() {
+	if t.snapshot == "" {
+		return
+	}
+	t.stopRevertHelper()
+	shellout("rm -rf " + t.snapshot)
+	log.Printf("Changes confirmed")
+	t.snapshot = ""
+	t.drawStatusBar()
+}
+
+// RevertChanges restores the rules saved before the changes were applied.
+func (t *Tui) RevertChanges() {
+	if t.snapshot == "" {
+		return
+	}
+	t.stopRevertHelper()
+	if _, trace, err := shellout(revertScript(t.snapshot)); err != nil {
+		log.Printf("Failed to revert changes: %s", trace)
+	} else {
+		log.Printf("Changes reverted")
+	}
+	t.snapshot = ""
+	t.ReloadTable()
+}
+
+func (t *Tui) CreateRevertCountdown() {
+	modal := t.styleModal(tview.NewModal())
+	deadline := time.Now().Add(t.revertAfter)
+
+	// The key which applied the changes must not confirm them as well, even
+	// when held down: only a key pressed afresh once the countdown shows does
+	armed := false
+	previous, previousAt := t.lastKey, t.lastKeyAt
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		stroke, now := strokeOf(event), time.Now()
+		repeated := stroke == previous && now.Sub(previousAt) < keyRepeatDelay
+		previous, previousAt = stroke, now
+		if repeated && !armed {
+			return nil
+		}
+		armed = true
+		return event
+	})
+	modal.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftDown {
+			armed = true
+		}
+		return action, event
+	})
+
+	text := func() string {
+		return fmt.Sprintf("Changes applied.\nThey will be reverted in %ds unless you keep them.",
+			int(time.Until(deadline).Seconds()+0.5))
+	}
+
+	stop := make(chan struct{})
+	t.revertStop = stop
+
+	focused := t.app.GetFocus()
+	closeCountdown := func() {
+		t.pages.RemovePage("revert")
+		t.app.SetFocus(focused)
+	}
+
+	t.pages.AddPage("revert", modal.SetText(text()).AddButtons([]string{"Keep changes", "Revert now"}).SetDoneFunc(func(i int, label string) {
+		if !armed {
+			return
+		}
+		switch label {
+		case "Keep changes":
+			t.ConfirmChanges()
+		case "Revert now":
+			t.RevertChanges()
+		default:
+			return
+		}
+		closeCountdown()
+	}), true, true)
+	t.app.SetFocus(modal)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t.app.QueueUpdateDraw(func() {
+					if t.revertStop != stop {
+						return
+					}
+					if time.Now().Before(deadline) {
+						modal.SetText(text())
+						return
+					}
+					t.RevertChanges()
+					closeCountdown()
+					t.CreateMessage("No confirmation received, changes have been reverted.", func() {
+						t.app.SetFocus(focused)
+					})
+				})
+			}
+		}
+	}()
+}
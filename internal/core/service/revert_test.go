@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestSafeApply(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		switch {
+		case cmd == "mktemp -d /var/tmp/tufw-revert.XXXXXX":
+			return "/var/tmp/tufw-revert.abc123\n", "", nil
+		case strings.HasPrefix(cmd, "setsid"):
+			return "4242\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetRevertTimer(90 * time.Second)
+	tui.Init()
+	tui.CreateLayout()
+
+	tui.SafeApply(func() { shellout("ufw allow 22/tcp") })
+	tui.ConfirmChanges()
+
+	expected := []string{
+		"mktemp -d /var/tmp/tufw-revert.XXXXXX",
+		"cp -p /etc/ufw/user.rules /etc/ufw/user6.rules /var/tmp/tufw-revert.abc123/ && ufw status | head -n 1 > /var/tmp/tufw-revert.abc123/status",
+		"setsid nohup bash -c 'sleep 90; [ -d /var/tmp/tufw-revert.abc123 ] && { " + revertScript("/var/tmp/tufw-revert.abc123") + "; }' </dev/null >/dev/null 2>&1 & echo $!",
+		"ufw allow 22/tcp",
+		"kill 4242 2>/dev/null",
+		"rm -rf /var/tmp/tufw-revert.abc123",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands:\n%q\nbut got:\n%q", expected, commands)
+	}
+	if tui.snapshot != "" {
+		t.Errorf("expected the snapshot to be released, got %q", tui.snapshot)
+	}
+}
+
+func TestSafeApply_HelperFailure(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		switch {
+		case cmd == "mktemp -d /var/tmp/tufw-revert.XXXXXX":
+			return "/var/tmp/tufw-revert.abc123\n", "", nil
+		case strings.HasPrefix(cmd, "setsid"):
+			return "", "bash: setsid: command not found", errors.New("exit status 127")
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetRevertTimer(90 * time.Second)
+	tui.Init()
+	tui.CreateLayout()
+
+	applied := false
+	tui.SafeApply(func() { applied = true })
+
+	if applied {
+		t.Errorf("changes which cannot be reverted should not be applied")
+	}
+	if name, _ := tui.pages.GetFrontPage(); name != "message" {
+		t.Errorf("the failure should be reported")
+	}
+	if tui.snapshot != "" || commands[len(commands)-1] != "rm -rf /var/tmp/tufw-revert.abc123" {
+		t.Errorf("expected the snapshot to be released, got %q", commands)
+	}
+}
+
+func TestSafeApply_Disabled(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.SafeApply(func() { shellout("ufw allow 22/tcp") })
+
+	if !reflect.DeepEqual(commands, []string{"ufw allow 22/tcp"}) {
+		t.Errorf("expected the change to be applied alone, got %q", commands)
+	}
+}
+
+func TestRevertCountdown_FreshKey(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) { return "", "", nil }
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetRevertTimer(90 * time.Second)
+	tui.Init()
+	tui.CreateLayout()
+	tui.snapshot = "/var/tmp/tufw-revert.abc123"
+
+	// The changes were applied with <Enter>, which is still held down
+	enter := tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+	tui.lastKey, tui.lastKeyAt = strokeOf(enter), time.Now()
+	tui.CreateRevertCountdown()
+	defer tui.stopRevertHelper()
+
+	_, page := tui.pages.GetFrontPage()
+	press := func(event *tcell.EventKey) {
+		page.InputHandler()(event, func(p tview.Primitive) {})
+	}
+
+	press(enter)
+	press(enter)
+	if tui.snapshot == "" {
+		t.Fatalf("a held key should not confirm the changes")
+	}
+
+	press(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+	press(enter)
+	if tui.snapshot != "" || tui.pages.HasPage("revert") {
+		t.Errorf("a fresh key press should confirm the changes")
+	}
+}
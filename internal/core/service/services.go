@@ -0,0 +1,60 @@
+package service
+
+import (
+	"log"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+const servicesFile = "/etc/services"
+
+// LoadServices reads the service names shown in the Port column and accepted by the Port field,
+// from the host the firewall is on.
+func (t *Tui) LoadServices() {
+	content, trace, err := shellout("cat " + servicesFile)
+	if err != nil {
+		log.Printf("Service names unavailable: %s - %v", strings.TrimSpace(trace), err)
+		t.services = nil
+		return
+	}
+	t.services = utils.ParseServices(content)
+}
+
+// portLabel shows the service names next to the ports, e.g. "22 (ssh)".
+func (t *Tui) portLabel(cellValues *domain.CellValues) string {
+	names := t.services.Names(cellValues.Port, utils.ParseProtocol(cellValues.To))
+	if names == "" {
+		return cellValues.Port
+	}
+	return cellValues.Port + " (" + names + ")"
+}
+
+// portFromCell returns the port of a Port cell without its service names.
+func portFromCell(text string) string {
+	if idx := strings.Index(text, " ("); idx != -1 {
+		text = text[:idx]
+	}
+	if text == "-" {
+		return ""
+	}
+	return text
+}
+
+// portField is the Port input of the rule forms, completing service names.
+func (t *Tui) portField(value string) *tview.InputField {
+	field := tview.NewInputField().
+		SetLabel("Port").
+		SetText(value).
+		SetFieldWidth(20).
+		SetAcceptanceFunc(utils.AcceptPortInput)
+
+	field.SetAutocompleteFunc(t.services.Complete)
+	field.SetAutocompletedFunc(func(text string, index int, source int) bool {
+		field.SetText(text)
+		return source != tview.AutocompletedNavigate
+	})
+	return field
+}
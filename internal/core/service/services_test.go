@@ -0,0 +1,96 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+)
+
+var testServices = utils.ParseServices("ssh 22/tcp\nhttp 80/tcp www\nhttps 443/tcp\nhttps 443/udp\npostgresql 5432/tcp postgres\n")
+
+func TestCreateRule_ResolvesServiceNames(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.services = testServices
+	populateForm(tui.form, domain.FormValues{Port: "postgres", Action: "ALLOW IN", From: "10.0.0.0/8"})
+
+	tui.CreateRule()
+
+	expected := []string{
+		"ufw --dry-run allow in from 10.0.0.0/8 to any proto tcp port 5432",
+		"ufw allow in from 10.0.0.0/8 to any proto tcp port 5432",
+	}
+	if !reflect.DeepEqual(commands[:2], expected) {
+		t.Errorf("expected commands:\n%q\nbut got:\n%q", expected, commands)
+	}
+
+	commands = nil
+	populateForm(tui.form, domain.FormValues{Port: "gopher", Action: "ALLOW IN"})
+	tui.CreateRule()
+	if len(commands) > 0 {
+		t.Errorf("expected an unknown service to be rejected, got %q", commands)
+	}
+}
+
+func TestPortLabel(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+	shellout = func(cmd string) (string, string, error) {
+		return strings.Join([]string{
+			"[ 1] 22/tcp ALLOW IN Anywhere",
+			"[ 2] 80,443/tcp ALLOW IN Anywhere",
+			"[ 3] 6000:6007/tcp ALLOW IN Anywhere",
+		}, "\n"), "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.services = testServices
+	tui.ReloadTable()
+
+	expected := []string{"22 (ssh)", "80,443 (http,https)", "6000:6007"}
+	got := columnTexts(tui, 2)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+	for i, text := range got {
+		if port := portFromCell(text); port != strings.Fields(expected[i])[0] {
+			t.Errorf("expected the port of %q, got %q", text, port)
+		}
+	}
+}
+
+func TestLoadServices(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		return "ssh 22/tcp\nhttp 80/tcp www\n", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.LoadServices()
+
+	// The names are the ones of the host the firewall is on
+	if !reflect.DeepEqual(commands, []string{"cat /etc/services"}) {
+		t.Errorf("expected /etc/services to be read through shellout, got %q", commands)
+	}
+	if names := tui.services.Names("80", ""); names != "http" {
+		t.Errorf("expected http, got %q", names)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+// TestTraffic walks the active ruleset and the default policies like ufw
+// would for the packet, highlighting the first matching rule in the table.
+// It returns the verdict and that rule, nil when a default policy applies.
+func (t *Tui) TestTraffic(p domain.Packet) (string, *domain.Rule) {
+	verdict, rule := utils.Simulate(t.resolveApps(t.LoadRules()), t.LoadDefaults(), p)
+
+	t.highlights = map[int]tcell.Color{}
+	if rule != nil {
+		t.highlights[rule.Number] = t.verdictColor(verdict)
+	}
+	t.ReloadTable()
+
+	if rule != nil {
+		for row := 1; row < t.table.GetRowCount(); row++ {
+			if t.RuleNumber(row) == rule.Number {
+				t.table.Select(row, 0)
+				break
+			}
+		}
+	}
+
+	return verdict, rule
+}
+
+func (t *Tui) verdictColor(verdict string) tcell.Color {
+	if utils.IsAllowed(verdict) {
+		return t.theme.Success
+	}
+	return t.theme.Error
+}
+
+func specificAddress(address string) bool {
+	return address != "" && address != "any"
+}
+
+func validatePacket(p domain.Packet) error {
+	for _, address := range []string{p.From, p.To} {
+		if address != "" && !utils.IsAddress(address) {
+			return fmt.Errorf("invalid address %q", address)
+		}
+	}
+	if specificAddress(p.From) && specificAddress(p.To) && strings.Contains(p.From, ":") != strings.Contains(p.To, ":") {
+		return fmt.Errorf("source and destination must both be IPv4 or IPv6")
+	}
+	if p.Port != "" && (!utils.IsPortSpec(p.Port) || strings.ContainsAny(p.Port, ",:")) {
+		return fmt.Errorf("invalid port %q", p.Port)
+	}
+	return nil
+}
+
+func (t *Tui) TrafficForm() {
+	t.help.SetText("Check whether a connection would be allowed").SetBorderPadding(1, 0, 1, 1)
+	interfaces, _ := t.LoadInterfaces()
+
+	ifaceInDropDown, ifaceOutDropDown := interfaceDropDowns(interfaces, "", "")
+
+	t.form.AddDropDown("Direction", []string{"IN", "OUT", "FWD"}, 0, nil).
+		AddInputField("From", "", 40, nil, nil).
+		AddInputField("To", "", 40, nil, nil).
+		AddInputField("Port", "", 20, nil, nil).
+		AddDropDown("Protocol", []string{"", "tcp", "udp"}, 0, nil).
+		AddFormItem(ifaceInDropDown).
+		AddButton("Test", func() {
+			fv := t.ParseFormValues()
+			_, direction := t.form.GetFormItemByLabel("Direction").(*tview.DropDown).GetCurrentOption()
+
+			p := domain.Packet{
+				Direction: direction,
+				From:      strings.TrimSpace(fv.From),
+				To:        strings.TrimSpace(fv.To),
+				Port:      strings.TrimSpace(fv.Port),
+				Protocol:  fv.Protocol,
+				Interface: fv.Interface,
+			}
+			switch direction {
+			case "OUT":
+				p.Interface = fv.InterfaceOut
+			case "FWD":
+				p.InterfaceOut = fv.InterfaceOut
+			}
+
+			if err := validatePacket(p); err != nil {
+				t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+				return
+			}
+
+			verdict, rule := t.TestTraffic(p)
+			reason := fmt.Sprintf("default %s policy", utils.PolicyKeys[direction])
+			if rule != nil {
+				reason = fmt.Sprintf("rule [%d] %s", rule.Number, utils.DescribeRule(*rule))
+			}
+			t.secondHelp.SetText(fmt.Sprintf(" %s by %s", verdict, reason)).SetTextColor(t.verdictColor(verdict))
+		}).
+		AddButton("Cancel", func() {
+			t.highlights = nil
+			t.Reset()
+			t.ReloadTable()
+			t.app.SetFocus(t.menu)
+		})
+
+	t.form.GetFormItemByLabel("Direction").(*tview.DropDown).SetSelectedFunc(func(direction string, index int) {
+		t.toggleInterfaces(direction, ifaceInDropDown, ifaceOutDropDown)
+	})
+
+	t.secondHelp.SetText("Empty addresses and port match any, the first matching rule decides").SetTextColor(t.theme.Accent).SetBorderPadding(0, 0, 1, 1)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestTestTraffic(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		switch {
+		case strings.HasPrefix(cmd, "ufw status numbered"):
+			return strings.Join(statusRows, "\n"), "", nil
+		case cmd == "ufw status verbose":
+			return "Status: active\nDefault: deny (incoming), allow (outgoing), disabled (routed)\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+
+	tests := []struct {
+		packet   domain.Packet
+		verdict  string
+		expected int
+	}{
+		{domain.Packet{Direction: "IN", From: "10.0.0.5", Port: "443", Protocol: "tcp"}, "DENY", 2},
+		{domain.Packet{Direction: "IN", From: "192.168.1.2", Port: "22", Protocol: "tcp"}, "ALLOW", 1},
+		{domain.Packet{Direction: "IN", From: "192.168.1.2", Port: "8080", Interface: "wg0"}, "ALLOW", 3},
+		{domain.Packet{Direction: "IN", From: "192.168.1.2", Port: "8080"}, "DENY", 0},
+		{domain.Packet{Direction: "OUT", To: "1.1.1.1", Port: "53", Interface: "eth0"}, "ALLOW", 5},
+		{domain.Packet{Direction: "FWD", From: "10.0.0.5", To: "10.0.1.5"}, "DISABLED", 0},
+	}
+
+	for _, tt := range tests {
+		verdict, rule := tui.TestTraffic(tt.packet)
+		if verdict != tt.verdict {
+			t.Errorf("%+v: expected %s, got %s", tt.packet, tt.verdict, verdict)
+		}
+
+		number := 0
+		if rule != nil {
+			number = rule.Number
+		}
+		if number != tt.expected {
+			t.Errorf("%+v: expected rule %d to match, got %d", tt.packet, tt.expected, number)
+			continue
+		}
+		if number == 0 {
+			continue
+		}
+
+		row, _ := tui.table.GetSelection()
+		if tui.RuleNumber(row) != number {
+			t.Errorf("%+v: expected rule %d to be selected, got row %d", tt.packet, number, row)
+		}
+		if _, bg, _ := tui.table.GetCell(row, 1).Style.Decompose(); bg != tui.verdictColor(verdict) {
+			t.Errorf("%+v: expected the matching rule to be highlighted, got %v", tt.packet, bg)
+		}
+	}
+}
+
+func TestValidatePacket(t *testing.T) {
+	valid := []domain.Packet{
+		{Direction: "IN"},
+		{Direction: "IN", From: "10.0.0.5", To: "10.0.0.1", Port: "22"},
+		{Direction: "IN", From: "2001:db8::1", To: "any"},
+	}
+	for _, p := range valid {
+		if err := validatePacket(p); err != nil {
+			t.Errorf("%+v: unexpected error %v", p, err)
+		}
+	}
+
+	invalid := []domain.Packet{
+		{Direction: "IN", From: "10.0.0.500"},
+		{Direction: "IN", From: "10.0.0.5", To: "2001:db8::1"},
+		{Direction: "IN", Port: "80,443"},
+		{Direction: "IN", Port: "http"},
+	}
+	for _, p := range invalid {
+		if err := validatePacket(p); err == nil {
+			t.Errorf("%+v: expected an error", p)
+		}
+	}
+}
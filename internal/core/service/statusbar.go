@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+)
+
+// LoadStatus reads the state of ufw. While it is inactive, `ufw status
+// verbose` only tells so and the rest is read from its configuration files.
+func (t *Tui) LoadStatus() domain.Status {
+	out, _, err := shellout("ufw status verbose")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	status := utils.ParseStatus(out)
+	if len(status.Defaults) == 0 {
+		out, _, _ = shellout("cat /etc/default/ufw")
+		status.Defaults = utils.ParseDefaultsFile(out)
+	}
+	if status.Logging == "" {
+		out, _, _ = shellout("cat /etc/ufw/ufw.conf")
+		status.Logging = utils.ParseLogLevel(out)
+	}
+	return status
+}
+
+// RefreshStatusBar reloads the state of ufw shown in the status bar, along
+// with the number of rules of the Status rows.
+func (t *Tui) RefreshStatusBar(rows []string) {
+	t.status = t.LoadStatus()
+	t.v4Rules, t.v6Rules, t.addedRules = 0, 0, 0
+	for _, row := range rows {
+		if !strings.HasPrefix(row, "[") {
+			continue
+		}
+		if strings.Contains(row, "(v6)") {
+			t.v6Rules++
+		} else {
+			t.v4Rules++
+		}
+	}
+
+	// Rules added while ufw is inactive are not listed by `ufw status`
+	if !t.status.Active {
+		for _, rule := range t.LoadAddedRules() {
+			if rule.V6 {
+				t.v6Rules++
+			} else {
+				t.v4Rules++
+			}
+			t.addedRules++
+		}
+	}
+
+	t.refreshedAt = time.Now()
+	t.drawStatusBar()
+}
+
+// drawStatusBar writes the last state loaded into the status bar.
+func (t *Tui) drawStatusBar() {
+	state, stateColor := "inactive", t.theme.Error
+	if t.status.Active {
+		state, stateColor = "active", t.theme.Success
+	}
+
+	var defaults []string
+	for _, direction := range []string{"incoming", "outgoing", "routed"} {
+		policy := t.status.Defaults[direction]
+		if policy == "" {
+			policy = "?"
+		}
+		defaults = append(defaults, fmt.Sprintf("%s (%s)", policy, direction))
+	}
+
+	logging := t.status.Logging
+	if logging == "" {
+		logging = "?"
+	}
+
+	changes, changesColor := "No pending changes", t.theme.Text
+	switch {
+	case t.snapshot != "":
+		changes, changesColor = "Changes not confirmed yet", t.theme.Warning
+	case t.addedRules > 0:
+		changes, changesColor = fmt.Sprintf("%d rule(s) not applied until ufw is enabled", t.addedRules), t.theme.Warning
+	}
+
+	t.statusBar.SetText(strings.Join([]string{
+		fmt.Sprintf(" ufw [%s]%s[-]", stateColor.String(), state),
+		"Default: " + strings.Join(defaults, ", "),
+		"Logging: " + logging,
+		fmt.Sprintf("Rules: %d v4, %d v6", t.v4Rules, t.v6Rules),
+		"Refreshed at " + t.refreshedAt.Format("15:04:05"),
+		fmt.Sprintf("[%s]%s[-]", changesColor.String(), changes),
+	}, " │ "))
+}
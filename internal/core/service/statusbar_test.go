@@ -0,0 +1,88 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestStatusBar(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	rows := []string{
+		"[ 1] 22/tcp ALLOW IN Anywhere",
+		"[ 2] 80/tcp ALLOW IN Anywhere",
+		"[ 3] 22/tcp (v6) ALLOW IN Anywhere (v6)",
+	}
+	shellout = func(cmd string) (string, string, error) {
+		switch {
+		case cmd == "ufw status verbose":
+			return "Status: active\nLogging: on (medium)\nDefault: deny (incoming), allow (outgoing), disabled (routed)\n", "", nil
+		case strings.HasPrefix(cmd, "ufw status numbered"):
+			return strings.Join(rows, "\n"), "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.ReloadTable()
+
+	text := tui.statusBar.GetText(true)
+	for _, expected := range []string{
+		"ufw active",
+		"Default: deny (incoming), allow (outgoing), disabled (routed)",
+		"Logging: on (medium)",
+		"Rules: 2 v4, 1 v6",
+		"Refreshed at " + tui.refreshedAt.Format("15:04:05"),
+		"No pending changes",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("%q does not mention %q", text, expected)
+		}
+	}
+
+	tui.snapshot = "/tmp/tufw-snapshot"
+	tui.ReloadTable()
+	if text := tui.statusBar.GetText(true); !strings.Contains(text, "Changes not confirmed yet") {
+		t.Errorf("%q does not mention the changes to confirm", text)
+	}
+}
+
+func TestStatusBar_Inactive(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		switch cmd {
+		case "ufw status verbose":
+			return "Status: inactive\n", "", nil
+		case "cat /etc/default/ufw":
+			return "DEFAULT_INPUT_POLICY=\"DROP\"\nDEFAULT_OUTPUT_POLICY=\"ACCEPT\"\nDEFAULT_FORWARD_POLICY=\"DROP\"\n", "", nil
+		case "cat /etc/ufw/ufw.conf":
+			return "ENABLED=no\nLOGLEVEL=low\n", "", nil
+		case "ufw show added":
+			return "Added user rules (see 'ufw status' for running firewall):\nufw allow 22/tcp\n", "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.ReloadTable()
+
+	text := tui.statusBar.GetText(true)
+	for _, expected := range []string{
+		"ufw inactive",
+		"Default: deny (incoming), allow (outgoing), deny (routed)",
+		"Logging: on (low)",
+		"Rules: 1 v4, 1 v6",
+		"2 rule(s) not applied until ufw is enabled",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("%q does not mention %q", text, expected)
+		}
+	}
+}
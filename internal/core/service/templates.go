@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+// SetTemplateDirs changes the directories user templates are read from.
+func (t *Tui) SetTemplateDirs(dirs ...string) {
+	t.templateDirs = dirs
+}
+
+func (t *Tui) templatesDirs() []string {
+	if t.templateDirs != nil {
+		return t.templateDirs
+	}
+
+	dirs := []string{"/etc/tufw/templates"}
+	if config, err := userConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(config, "tufw", "templates"))
+	}
+	return dirs
+}
+
+// LoadTemplates returns the built-in templates followed by the YAML ones of
+// the template directories, which replace built-in ones of the same name.
+func (t *Tui) LoadTemplates() []utils.Template {
+	templates := utils.BuiltinTemplates()
+
+	for _, dir := range t.templatesDirs() {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.y*ml"))
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				log.Printf("Skipping template %s: %v", file, err)
+				continue
+			}
+			tpl, err := utils.ParseTemplate(data)
+			if err != nil {
+				log.Printf("Skipping template %s: %v", file, err)
+				continue
+			}
+
+			replaced := false
+			for i := range templates {
+				if templates[i].Name == tpl.Name {
+					templates[i], replaced = tpl, true
+				}
+			}
+			if !replaced {
+				templates = append(templates, tpl)
+			}
+		}
+	}
+	return templates
+}
+
+// ApplyTemplate adds the rules of a template filled with values, through the
+// same dry-run and apply steps as a single rule.
+func (t *Tui) ApplyTemplate(tpl utils.Template, values map[string]string) error {
+	rules, err := tpl.Expand(values)
+	if err != nil {
+		return err
+	}
+
+	var expanded []domain.FormValues
+	for _, rule := range rules {
+		if errs := utils.ValidateRule(rule, t.services); len(errs) > 0 {
+			return errs[0]
+		}
+		if rule.Port, rule.Protocol, err = t.services.Resolve(rule.Port, rule.Protocol); err != nil {
+			return err
+		}
+		if !utils.IsGroup(rule.To) && !utils.IsGroup(rule.From) {
+			expanded = append(expanded, rule)
+			continue
+		}
+		members, err := t.expandGroups(rule)
+		if err != nil {
+			return err
+		}
+		expanded = append(expanded, members...)
+	}
+
+	commands := make([]string, len(expanded))
+	for i, rule := range expanded {
+		commands[i] = ruleCommand(rule, 0)
+	}
+	t.applyRules(nil, expanded, commands, func() {})
+	return nil
+}
+
+func (t *Tui) TemplateForm() {
+	t.formHelp()
+
+	templates := t.LoadTemplates()
+	names := make([]string, len(templates))
+	for i, tpl := range templates {
+		names[i] = tpl.Name
+	}
+
+	t.form.AddDropDown("Template", names, 0, func(name string, index int) {
+		if index >= 0 {
+			t.secondHelp.SetText(templates[index].Description).SetTextColor(t.theme.Accent)
+		}
+	}).
+		AddButton("Next", func() {
+			index, _ := t.form.GetFormItemByLabel("Template").(*tview.DropDown).GetCurrentOption()
+			t.form.Clear(true)
+			t.templateParamsForm(templates[index])
+			t.app.SetFocus(t.form)
+		}).
+		AddButton("Cancel", func() {
+			t.Reset()
+			t.app.SetFocus(t.menu)
+		})
+
+	t.secondHelp.SetBorderPadding(0, 0, 1, 1)
+	if len(templates) > 0 {
+		t.secondHelp.SetText(templates[0].Description).SetTextColor(t.theme.Accent)
+	}
+}
+
+func (t *Tui) templateParamsForm(tpl utils.Template) {
+	for _, param := range tpl.Params {
+		label := param.Label
+		if label == "" {
+			label = param.Name
+		}
+		if param.Required {
+			label += " *"
+		}
+		t.form.AddInputField(label, param.Default, 30, nil, nil)
+	}
+
+	t.form.AddButton("Apply", func() {
+		values := map[string]string{}
+		for i, param := range tpl.Params {
+			values[param.Name] = t.form.GetFormItem(i).(*tview.InputField).GetText()
+		}
+		if err := t.ApplyTemplate(tpl, values); err != nil {
+			t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+		}
+	}).
+		AddButton("Back", func() {
+			t.form.Clear(true)
+			t.TemplateForm()
+			t.app.SetFocus(t.form)
+		}).
+		AddButton("Cancel", func() {
+			t.Reset()
+			t.app.SetFocus(t.menu)
+		})
+
+	lines := []string{tpl.Description, ""}
+	for _, rule := range tpl.Rules {
+		lines = append(lines, fmt.Sprintf("%s %s", rule.Action, strings.TrimSpace(strings.Join([]string{rule.Port, rule.Protocol, rule.From, rule.Interface}, " "))))
+	}
+	t.secondHelp.SetText(strings.Join(lines, "\n")).SetTextColor(t.theme.Accent)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/utils"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	custom := "name: Web server\nrules:\n  - action: ALLOW IN\n    port: \"8443\"\n    protocol: tcp\n"
+	if err := os.WriteFile(filepath.Join(dir, "web.yaml"), []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	extra := "name: Redis\nrules:\n  - action: ALLOW IN\n    port: \"6379\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "redis.yml"), []byte(extra), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("name: [broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetTemplateDirs(dir)
+	templates := tui.LoadTemplates()
+
+	if len(templates) != len(utils.BuiltinTemplates())+1 {
+		t.Fatalf("expected the built-in templates and Redis, got %d templates", len(templates))
+	}
+	for _, tpl := range templates {
+		if tpl.Name == "Web server" && tpl.Rules[0].Port != "8443" {
+			t.Errorf("expected the custom web template to replace the built-in one, got %+v", tpl)
+		}
+	}
+	if templates[len(templates)-1].Name != "Redis" {
+		t.Errorf("expected Redis to be added last, got %q", templates[len(templates)-1].Name)
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ufw --dry-run") || strings.HasPrefix(cmd, "ufw allow") || strings.HasPrefix(cmd, "ufw route") {
+			commands = append(commands, cmd)
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.SetTemplateDirs(t.TempDir())
+	tui.Init()
+	tui.CreateLayout()
+
+	var wireguard utils.Template
+	for _, tpl := range tui.LoadTemplates() {
+		if tpl.Name == "WireGuard" {
+			wireguard = tpl
+		}
+	}
+
+	if err := tui.ApplyTemplate(wireguard, map[string]string{"port": "51821"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"ufw --dry-run allow in from any to any proto udp port 51821 comment 'WireGuard'",
+		"ufw --dry-run route allow in on wg0 from any to any comment 'WireGuard'",
+		"ufw allow in from any to any proto udp port 51821 comment 'WireGuard'",
+		"ufw route allow in on wg0 from any to any comment 'WireGuard'",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands:\n%q\nbut got:\n%q", expected, commands)
+	}
+}
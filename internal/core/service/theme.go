@@ -0,0 +1,312 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme holds the colors of the interface.
+type Theme struct {
+	Accent       tcell.Color // Menu shortcuts, help texts and marked rows
+	Background   tcell.Color
+	Border       tcell.Color
+	Title        tcell.Color
+	Text         tcell.Color
+	Header       tcell.Color
+	Selected     tcell.Color // Background of the selected row
+	SelectedText tcell.Color
+	Field        tcell.Color
+	FieldText    tcell.Color
+	Label        tcell.Color
+	Button       tcell.Color
+	ButtonText   tcell.Color
+	Modal        tcell.Color
+	ModalText    tcell.Color
+	Error        tcell.Color
+	Warning      tcell.Color
+	Success      tcell.Color
+	Actions      map[string]tcell.Color // Keyed by ALLOW, DENY, REJECT and LIMIT
+	Mono         bool                   // Rely on text attributes only, see https://no-color.org
+}
+
+var themes = map[string]Theme{
+	"dark": {
+		Accent:       tcell.ColorDarkCyan,
+		Background:   tcell.ColorBlack,
+		Border:       tcell.ColorWhite,
+		Title:        tcell.ColorWhite,
+		Text:         tcell.ColorWhite,
+		Header:       tcell.ColorDarkCyan,
+		Selected:     tcell.ColorWhite,
+		SelectedText: tcell.ColorBlack,
+		Field:        tcell.ColorDarkCyan,
+		FieldText:    tcell.ColorWhite,
+		Label:        tcell.ColorWhite,
+		Button:       tcell.ColorDarkCyan,
+		ButtonText:   tcell.ColorWhite,
+		Modal:        tcell.ColorBlue,
+		ModalText:    tcell.ColorWhite,
+		Error:        tcell.ColorRed,
+		Warning:      tcell.ColorYellow,
+		Success:      tcell.ColorGreen,
+		Actions: map[string]tcell.Color{
+			"ALLOW": tcell.ColorGreen, "DENY": tcell.ColorRed, "REJECT": tcell.ColorOrangeRed, "LIMIT": tcell.ColorYellow,
+		},
+	},
+	"light": {
+		Accent:       tcell.ColorBlue,
+		Background:   tcell.ColorWhite,
+		Border:       tcell.ColorGray,
+		Title:        tcell.ColorBlack,
+		Text:         tcell.ColorBlack,
+		Header:       tcell.ColorBlue,
+		Selected:     tcell.ColorLightSkyBlue,
+		SelectedText: tcell.ColorBlack,
+		Field:        tcell.ColorLightGray,
+		FieldText:    tcell.ColorBlack,
+		Label:        tcell.ColorBlack,
+		Button:       tcell.ColorBlue,
+		ButtonText:   tcell.ColorWhite,
+		Modal:        tcell.ColorLightGray,
+		ModalText:    tcell.ColorBlack,
+		Error:        tcell.ColorDarkRed,
+		Warning:      tcell.ColorDarkOrange,
+		Success:      tcell.ColorDarkGreen,
+		Actions: map[string]tcell.Color{
+			"ALLOW": tcell.ColorDarkGreen, "DENY": tcell.ColorDarkRed, "REJECT": tcell.ColorOrangeRed, "LIMIT": tcell.ColorDarkOrange,
+		},
+	},
+	"high-contrast": {
+		Accent:       tcell.ColorYellow,
+		Background:   tcell.ColorBlack,
+		Border:       tcell.ColorYellow,
+		Title:        tcell.ColorYellow,
+		Text:         tcell.ColorWhite,
+		Header:       tcell.ColorYellow,
+		Selected:     tcell.ColorYellow,
+		SelectedText: tcell.ColorBlack,
+		Field:        tcell.ColorWhite,
+		FieldText:    tcell.ColorBlack,
+		Label:        tcell.ColorYellow,
+		Button:       tcell.ColorYellow,
+		ButtonText:   tcell.ColorBlack,
+		Modal:        tcell.ColorBlack,
+		ModalText:    tcell.ColorWhite,
+		Error:        tcell.ColorRed,
+		Warning:      tcell.ColorYellow,
+		Success:      tcell.ColorLime,
+		Actions: map[string]tcell.Color{
+			"ALLOW": tcell.ColorLime, "DENY": tcell.ColorRed, "REJECT": tcell.ColorFuchsia, "LIMIT": tcell.ColorYellow,
+		},
+	},
+	"mono": {
+		Accent: tcell.ColorDefault, Background: tcell.ColorDefault, Border: tcell.ColorDefault, Title: tcell.ColorDefault,
+		Text: tcell.ColorDefault, Header: tcell.ColorDefault, Selected: tcell.ColorDefault, SelectedText: tcell.ColorDefault,
+		Field: tcell.ColorDefault, FieldText: tcell.ColorDefault, Label: tcell.ColorDefault, Button: tcell.ColorDefault,
+		ButtonText: tcell.ColorDefault, Modal: tcell.ColorDefault, ModalText: tcell.ColorDefault, Error: tcell.ColorDefault,
+		Warning: tcell.ColorDefault, Success: tcell.ColorDefault, Actions: map[string]tcell.Color{}, Mono: true,
+	},
+}
+
+// ThemeNames lists the built-in themes.
+var ThemeNames = []string{"dark", "light", "high-contrast"}
+
+// themeFile is the YAML layout of a theme file. Colors are names or #rrggbb
+// values and the ones left out come from the base theme.
+type themeFile struct {
+	Base         string            `yaml:"base"`
+	Accent       string            `yaml:"accent"`
+	Background   string            `yaml:"background"`
+	Border       string            `yaml:"border"`
+	Title        string            `yaml:"title"`
+	Text         string            `yaml:"text"`
+	Header       string            `yaml:"header"`
+	Selected     string            `yaml:"selected"`
+	SelectedText string            `yaml:"selected_text"`
+	Field        string            `yaml:"field"`
+	FieldText    string            `yaml:"field_text"`
+	Label        string            `yaml:"label"`
+	Button       string            `yaml:"button"`
+	ButtonText   string            `yaml:"button_text"`
+	Modal        string            `yaml:"modal"`
+	ModalText    string            `yaml:"modal_text"`
+	Error        string            `yaml:"error"`
+	Warning      string            `yaml:"warning"`
+	Success      string            `yaml:"success"`
+	Actions      map[string]string `yaml:"actions"`
+}
+
+func parseColor(name string) (tcell.Color, error) {
+	color := tcell.GetColor(strings.ToLower(name))
+	if color == tcell.ColorDefault && name != "default" {
+		return color, fmt.Errorf("unknown color %q", name)
+	}
+	return color, nil
+}
+
+// ParseTheme reads a theme written in YAML on top of its base theme.
+func ParseTheme(data []byte) (Theme, error) {
+	var file themeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Theme{}, err
+	}
+
+	if file.Base == "" {
+		file.Base = "dark"
+	}
+	base, ok := themes[file.Base]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown base theme %q", file.Base)
+	}
+
+	theme := base
+	theme.Actions = map[string]tcell.Color{}
+	for action, color := range base.Actions {
+		theme.Actions[action] = color
+	}
+
+	fields := []struct {
+		value string
+		color *tcell.Color
+	}{
+		{file.Accent, &theme.Accent}, {file.Background, &theme.Background}, {file.Border, &theme.Border},
+		{file.Title, &theme.Title}, {file.Text, &theme.Text}, {file.Header, &theme.Header},
+		{file.Selected, &theme.Selected}, {file.SelectedText, &theme.SelectedText}, {file.Field, &theme.Field},
+		{file.FieldText, &theme.FieldText}, {file.Label, &theme.Label}, {file.Button, &theme.Button},
+		{file.ButtonText, &theme.ButtonText}, {file.Modal, &theme.Modal}, {file.ModalText, &theme.ModalText},
+		{file.Error, &theme.Error}, {file.Warning, &theme.Warning}, {file.Success, &theme.Success},
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		color, err := parseColor(field.value)
+		if err != nil {
+			return Theme{}, err
+		}
+		*field.color = color
+	}
+
+	for action, value := range file.Actions {
+		color, err := parseColor(value)
+		if err != nil {
+			return Theme{}, err
+		}
+		theme.Actions[strings.ToUpper(action)] = color
+	}
+
+	return theme, nil
+}
+
+// LoadTheme returns a built-in theme by name, or reads a theme file.
+// Colors are disabled whenever NO_COLOR is set.
+func LoadTheme(nameOrPath string) (Theme, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		return themes["mono"], nil
+	}
+	if theme, ok := themes[nameOrPath]; ok {
+		return theme, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return Theme{}, fmt.Errorf("unknown theme %q: %w", nameOrPath, err)
+	}
+	theme, err := ParseTheme(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", nameOrPath, err)
+	}
+	return theme, nil
+}
+
+// WithAccent replaces the accent color, along with the colors following it.
+func (th Theme) WithAccent(color tcell.Color) Theme {
+	if th.Mono {
+		return th
+	}
+	for _, c := range []*tcell.Color{&th.Header, &th.Field, &th.Button} {
+		if *c == th.Accent {
+			*c = color
+		}
+	}
+	th.Accent = color
+	return th
+}
+
+// actionColor returns the color of an action such as "DENY IN" or "ALLOW-OUT".
+func (th Theme) actionColor(action string) tcell.Color {
+	name, _, _ := strings.Cut(strings.ReplaceAll(action, "-", " "), " ")
+	if color, ok := th.Actions[name]; ok {
+		return color
+	}
+	return th.Text
+}
+
+// SetTheme changes the colors of the interface. It has to be called before
+// Init as tview primitives pick their default colors when created.
+func (t *Tui) SetTheme(theme Theme) {
+	t.theme = theme
+
+	tview.Styles = tview.Theme{
+		PrimitiveBackgroundColor:    theme.Background,
+		ContrastBackgroundColor:     theme.Field,
+		MoreContrastBackgroundColor: theme.Button,
+		BorderColor:                 theme.Border,
+		TitleColor:                  theme.Title,
+		GraphicsColor:               theme.Border,
+		PrimaryTextColor:            theme.Text,
+		SecondaryTextColor:          theme.Label,
+		TertiaryTextColor:           theme.Accent,
+		InverseTextColor:            theme.Background,
+		ContrastSecondaryTextColor:  theme.FieldText,
+	}
+}
+
+func (t *Tui) selectedStyle() tcell.Style {
+	if t.theme.Mono {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault.Background(t.theme.Selected).Foreground(t.theme.SelectedText)
+}
+
+// emphasize shows a marked or highlighted cell, underlining it when colors
+// are disabled.
+func (t *Tui) emphasize(cell *tview.TableCell, background tcell.Color, on bool) *tview.TableCell {
+	if t.theme.Mono {
+		if on {
+			return cell.SetAttributes(tcell.AttrUnderline)
+		}
+		return cell.SetAttributes(tcell.AttrNone)
+	}
+	return cell.SetBackgroundColor(background)
+}
+
+// styleForm applies the theme to a form, its fields and buttons.
+func (t *Tui) styleForm(form *tview.Form) *tview.Form {
+	if t.theme.Mono {
+		return form.SetFieldStyle(tcell.StyleDefault.Underline(true)).
+			SetButtonStyle(tcell.StyleDefault).
+			SetButtonActivatedStyle(tcell.StyleDefault.Reverse(true))
+	}
+	return form.SetButtonTextColor(t.theme.ButtonText).
+		SetButtonBackgroundColor(t.theme.Button).
+		SetFieldBackgroundColor(t.theme.Field).
+		SetFieldTextColor(t.theme.FieldText).
+		SetLabelColor(t.theme.Label)
+}
+
+// styleModal applies the theme to a modal and its buttons.
+func (t *Tui) styleModal(modal *tview.Modal) *tview.Modal {
+	if t.theme.Mono {
+		return modal.SetButtonStyle(tcell.StyleDefault).SetButtonActivatedStyle(tcell.StyleDefault.Reverse(true))
+	}
+	return modal.SetBackgroundColor(t.theme.Modal).
+		SetTextColor(t.theme.ModalText).
+		SetButtonBackgroundColor(t.theme.Button).
+		SetButtonTextColor(t.theme.ButtonText)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme([]byte(`
+base: light
+accent: "#ff8800"
+selected_text: white
+actions:
+  deny: purple
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if theme.Accent != tcell.NewHexColor(0xff8800) {
+		t.Errorf("accent: got %v", theme.Accent)
+	}
+	if theme.SelectedText != tcell.ColorWhite {
+		t.Errorf("selected text: got %v", theme.SelectedText)
+	}
+	if theme.Text != themes["light"].Text {
+		t.Errorf("text should come from the base theme, got %v", theme.Text)
+	}
+	if theme.actionColor("DENY IN") != tcell.ColorPurple || theme.actionColor("ALLOW OUT") != tcell.ColorDarkGreen {
+		t.Errorf("unexpected action colors %v", theme.Actions)
+	}
+	if themes["light"].Actions["DENY"] != tcell.ColorDarkRed {
+		t.Errorf("the base theme must not be modified")
+	}
+
+	invalid := []string{"base: solarized", "accent: notacolor", "actions:\n  allow: nope", "accent: [1"}
+	for _, input := range invalid {
+		if _, err := ParseTheme([]byte(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	for _, name := range ThemeNames {
+		if _, err := LoadTheme(name); err != nil {
+			t.Errorf("built-in theme %s: %v", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	if err := os.WriteFile(path, []byte("base: high-contrast\nborder: blue\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	theme, err := LoadTheme(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if theme.Border != tcell.ColorBlue || theme.Accent != tcell.ColorYellow {
+		t.Errorf("unexpected theme %+v", theme)
+	}
+
+	if _, err := LoadTheme("missing"); err == nil {
+		t.Errorf("expected an error for an unknown theme")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	theme, err = LoadTheme("light")
+	if err != nil || !theme.Mono {
+		t.Errorf("NO_COLOR must disable colors, got %+v, %v", theme, err)
+	}
+	if theme.actionColor("ALLOW IN") != tcell.ColorDefault {
+		t.Errorf("actions must not be colored without colors")
+	}
+}
+
+func TestWithAccent(t *testing.T) {
+	theme := themes["dark"].WithAccent(tcell.ColorRed)
+	if theme.Accent != tcell.ColorRed || theme.Field != tcell.ColorRed || theme.Button != tcell.ColorRed {
+		t.Errorf("the accent should follow the color, got %+v", theme)
+	}
+	if theme.Modal != themes["dark"].Modal {
+		t.Errorf("colors unrelated to the accent should be kept")
+	}
+
+	if mono := themes["mono"].WithAccent(tcell.ColorRed); mono.Accent != tcell.ColorDefault {
+		t.Errorf("the accent should be ignored without colors")
+	}
+}
+
+func TestActionColumnColor(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return "[ 1] 22/tcp ALLOW IN Anywhere\n[ 2] 23/tcp DENY IN Anywhere", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.ReloadTable()
+
+	for row, expected := range map[int]tcell.Color{1: tcell.ColorGreen, 2: tcell.ColorRed} {
+		if fg, _, _ := tui.table.GetCell(row, 3).Style.Decompose(); fg != expected {
+			t.Errorf("row %d: got %v, want %v", row, fg, expected)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+// SetTransport runs the commands through transport from now on and shows
+// it in the title bar.
+func (t *Tui) SetTransport(transport utils.Transport) {
+	t.transport = transport
+	utils.SetTransport(transport)
+	if t.titleBar != nil {
+		t.drawTitleBar()
+	}
+}
+
+// drawTitleBar shows where the firewall being managed is.
+func (t *Tui) drawTitleBar() {
+	if name := t.currentHost(); name != "" && name != t.transport.String() {
+		t.titleBar.SetText(fmt.Sprintf(" tufw · [%s]%s[-] (%s)", t.theme.Accent.String(), tview.Escape(name), tview.Escape(t.transport.String())))
+		return
+	}
+	t.titleBar.SetText(fmt.Sprintf(" tufw · [%s]%s[-]", t.theme.Accent.String(), tview.Escape(t.transport.String())))
+}
+
+// sshSession returns the ssh connection which would be cut by a rule, on the
+// host the commands run on. Containers and network namespaces are not
+// reached through the connection tufw runs in.
+func (t *Tui) sshSession() *domain.Packet {
+	switch {
+	case t.transport.Local() || strings.HasPrefix(t.transport.Prefix, "sudo"):
+		return sessionInterface(utils.ParseSSHConnection(os.Getenv("SSH_CONNECTION")), shellout)
+	case t.transport.Remote():
+		out, _, err := shellout("echo $SSH_CONNECTION")
+		if err != nil {
+			return nil
+		}
+		return sessionInterface(utils.ParseSSHConnection(strings.TrimSpace(out)), shellout)
+	}
+	return nil
+}
+
+// sessionInterface fills in the interface the session comes in through, the
+// one routing to its client, with run executing commands on its host. It is
+// left empty when it cannot be found.
+func sessionInterface(session *domain.Packet, run func(string) (string, string, error)) *domain.Packet {
+	if session == nil {
+		return nil
+	}
+	out, _, err := run("ip route get " + session.From)
+	if err != nil {
+		log.Printf("Interface of the SSH session unknown: %v", err)
+		return session
+	}
+	session.Interface = utils.ParseRouteInterface(out)
+	return session
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/peltho/tufw/internal/core/domain"
@@ -15,6 +19,13 @@ import (
 var (
 	NUMBER_OF_V6_RULES = 0
 	shellout           = utils.Shellout
+	columns            = []string{"#", "To", "Port", "Action", "From", "Interface", "Comment"}
+	actions            = []string{
+		"ALLOW IN", "DENY IN", "REJECT IN", "LIMIT IN",
+		"ALLOW OUT", "DENY OUT", "REJECT OUT", "LIMIT OUT",
+		"ALLOW FWD", "DENY FWD", "REJECT FWD", "LIMIT FWD",
+	}
+	protocols = []string{"", "tcp", "udp"}
 )
 
 type Tui struct {
@@ -24,22 +35,92 @@ type Tui struct {
 	menu       *tview.Flex
 	help       *tview.TextView
 	secondHelp *tview.TextView
+	details    *tview.TextView
+	statusBar  *tview.TextView
+	titleBar   *tview.TextView
+	hostsList  *tview.List
+	side       *tview.Flex
 	pages      *tview.Pages
-	color      tcell.Color
+	theme      Theme
+	marked     map[int]bool
+	anchor     int
+	findings   map[int]domain.Finding
+	highlights map[int]tcell.Color
+	session    *domain.Packet
+	ruleCount  int
+
+	status      domain.Status
+	v4Rules     int
+	v6Rules     int
+	addedRules  int
+	refreshedAt time.Time
+
+	sortColumn int
+	sortDesc   bool
+	filters    map[int]string
+
+	groupsFile   string
+	templateDirs []string
+	services     *utils.Services
+
+	defaultAction string
+	confirm       string
+	refreshEvery  time.Duration
+	mouse         bool
+	transport     utils.Transport
+	hosts         []Host
+	bindings      map[string][]string
+	registry      *Registry
+	layout        []int
+	rows          []*domain.CellValues
+
+	revertAfter time.Duration
+	revertPid   string
+	revertStop  chan struct{}
+	snapshot    string
+	lastKey     keyStroke
+	lastKeyAt   time.Time
 }
 
 func CreateApplication(color tcell.Color) *Tui {
-	tui := Tui{color: color}
+	tui := Tui{theme: themes["dark"].WithAccent(color)}
 	return &tui
 }
 
+// SetRevertTimer makes applied changes revert automatically after d unless
+// confirmed. A zero duration disables it.
+func (t *Tui) SetRevertTimer(d time.Duration) {
+	t.revertAfter = d
+}
+
 func (t *Tui) Init() {
 	t.app = tview.NewApplication()
+	t.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		t.lastKey, t.lastKeyAt = strokeOf(event), time.Now()
+		return event
+	})
 	t.table = tview.NewTable()
-	t.form = tview.NewForm()
+	t.form = t.styleForm(tview.NewForm())
 	t.menu = tview.NewFlex()
 	t.help = tview.NewTextView()
 	t.secondHelp = tview.NewTextView()
+	if t.bindings == nil {
+		t.bindings = Bindings("default", nil)
+	}
+	t.registry = NewRegistry(t.bindings)
+	t.tableActions()
+	t.table.SetInputCapture(t.tableInput)
+	t.registry.On(PaneForm, "form-help", func() { t.KeysOverlay(PaneForm) })
+	t.form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return t.registry.Handle(PaneForm, event)
+	})
+	t.table.SetMouseCapture(t.tableMouse)
+	t.details = tview.NewTextView()
+	t.statusBar = tview.NewTextView().SetDynamicColors(true)
+	t.titleBar = tview.NewTextView().SetDynamicColors(true)
+	t.hostsList = tview.NewList()
+	t.drawTitleBar()
+	t.side = tview.NewFlex()
 	t.pages = tview.NewPages()
 }
 
@@ -55,27 +136,27 @@ func (t *Tui) LoadInterfaces() ([]string, error) {
 	return interfaces, nil
 }
 
-func (t *Tui) LoadSearchData(needle string) ([]string, error) {
+func (t *Tui) LoadSearchData(query string) ([]string, error) {
 	output, err := t.LoadUFWOutput()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load UFW output: %w", err)
 	}
 
-	var rules []string
-	for _, row := range output {
-		rules = append(rules, utils.FormatUfwRule(row))
-	}
-
-	var matches []string
+	return searchRows(output, query)
+}
 
-	re, err := regexp.Compile("(?i)" + needle)
+// searchRows keeps the raw status rows whose parsed rule matches query.
+func searchRows(rows []string, query string) ([]string, error) {
+	q, err := utils.ParseQuery(query)
 	if err != nil {
-		return nil, fmt.Errorf("invalid search pattern: %w", err)
+		return nil, fmt.Errorf("invalid search query: %w", err)
 	}
 
-	for _, rule := range rules {
-		if re.MatchString(rule) {
-			matches = append(matches, rule)
+	var matches []string
+	for _, row := range rows {
+		rule := utils.ParseRule(row)
+		if rule != nil && q(*rule, utils.FormatUfwRule(row)) {
+			matches = append(matches, row)
 		}
 	}
 
@@ -94,6 +175,13 @@ func (t *Tui) LoadUFWOutput() ([]string, error) {
 	}
 	rows := strings.Split(out, "\n")
 
+	t.ruleCount = 0
+	for _, row := range rows {
+		if strings.HasPrefix(row, "[") {
+			t.ruleCount++
+		}
+	}
+
 	return rows, nil
 }
 
@@ -116,79 +204,114 @@ func (t *Tui) LoadTableData() ([]string, error) {
 func (t *Tui) CreateTable(rows []string) {
 	t.table.SetFixed(1, 1).SetBorderPadding(1, 0, 1, 1)
 
-	columns := []string{"#", "To", "Port", "Action", "From", "Comment"}
+	var cells []*domain.CellValues
+	for _, row := range rows {
+		formattedRow := utils.FormatUfwRule(row)
 
-	for c := range columns {
-		t.table.SetCell(0, c, tview.NewTableCell(columns[c]).SetTextColor(t.color).SetAlign(tview.AlignCenter))
-		if c >= len(columns) {
-			break
+		cellValues := utils.FillCell(formattedRow)
+		if cellValues == nil || !t.matchesFilters(cellValues) {
+			continue
 		}
+		cells = append(cells, cellValues)
+	}
+	t.sortCells(cells)
+	t.rows = cells
 
-		for r, row := range rows {
-			if r > len(rows)-1 {
-				break
-			}
+	for d, c := range t.displayedColumns() {
+		t.table.SetCell(0, d, tview.NewTableCell(columns[c]+t.sortIndicator(c)).SetTextColor(t.theme.Header).SetAlign(tview.AlignCenter))
 
-			formattedRow := utils.FormatUfwRule(row)
+		for r, cellValues := range cells {
+			number := indexNumber(cellValues.Index)
 
-			cellValues := utils.FillCell(formattedRow)
-			if cellValues == nil {
-				continue
+			textColor := t.theme.Text
+			if c == 3 { // "Action"
+				textColor = t.theme.actionColor(cellValues.Action)
+			}
+			if finding, ok := t.findings[number]; ok {
+				textColor = t.findingColor(finding.Kind)
+			}
+
+			background := tcell.ColorDefault
+			if t.marked[number] {
+				background = t.theme.Accent
+			}
+			highlight, highlighted := t.highlights[number]
+			if highlighted {
+				background = highlight
 			}
 
 			// --- display values per column ---
 			alignment := tview.AlignCenter
-			value := ""
-			switch c {
-			case 0: // "#"
-				value = cellValues.Index
-			case 1: // "To"
-				value = cellValues.To
-			case 2: // "Port"
-				value = cellValues.Port
-			case 3: // "Action"
-				value = cellValues.Action
-			case 4: // "From"
-				value = cellValues.From
-			case 5: // "Comment"
-				value = cellValues.Comment
+			if c == 6 { // "Comment"
 				alignment = tview.AlignLeft
 			}
 
-			t.table.SetCell(r+1, c,
-				tview.NewTableCell(value).
-					SetTextColor(tcell.ColorWhite).
-					SetAlign(alignment).
-					SetExpansion(1),
-			)
+			text := cellValue(cellValues, c)
+			if c == 2 { // "Port"
+				text = t.portLabel(cellValues)
+			}
+
+			cell := tview.NewTableCell(text).
+				SetTextColor(textColor).
+				SetAlign(alignment).
+				SetExpansion(1)
+			t.table.SetCell(r+1, d, t.emphasize(cell, background, t.marked[number] || highlighted))
 		}
 	}
 
-	t.table.SetBorder(true).SetTitle(" Status ")
-	t.table.SetBorders(false).SetSeparator(tview.Borders.Vertical)
+	title := " Status "
+	if len(t.filters) > 0 {
+		title = fmt.Sprintf(" Status (%d shown, filtered) ", len(cells))
+	}
+	t.table.SetBorder(true).SetTitle(title)
+	t.table.SetBorders(false).SetSeparator(tview.Borders.Vertical).SetSelectedStyle(t.selectedStyle())
 
 	t.table.SetFocusFunc(func() {
 		t.table.SetSelectable(true, false)
 	})
 
-	t.table.Select(1, 0).SetFixed(1, 1).SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEscape {
-			t.table.SetSelectable(false, false)
-			t.help.Clear()
-			t.app.SetFocus(t.menu)
-		}
-	})
+	t.table.Select(1, 0).SetFixed(1, 1)
+}
+
+// leaveTable goes back to the menu, clearing what was shown on the table.
+func (t *Tui) leaveTable() {
+	t.ClearMarks()
+	t.table.SetSelectable(false, false)
+	if t.highlights != nil {
+		t.highlights = nil
+		t.ReloadTable()
+	}
+	t.help.Clear()
+	t.secondHelp.Clear()
+	if t.findings != nil {
+		t.findings = nil
+		t.HideDetails()
+		t.ReloadTable()
+	}
+	t.app.SetFocus(t.menu)
 }
 
 func (t *Tui) ReloadTable() {
+	if t.marked != nil {
+		t.marked = map[int]bool{}
+	}
 	t.table.Clear()
 	data, _ := t.LoadUFWOutput()
 
+	// Keep the analysis highlights in sync with the rules being displayed
+	if t.findings != nil {
+		t.findings = map[int]domain.Finding{}
+		for _, f := range utils.AnalyzeRules(utils.ParseRules(data)) {
+			t.findings[f.Rule] = f
+		}
+	}
+
 	t.CreateTable(data)
+	t.RefreshStatusBar(data)
 }
 
 func (t *Tui) CreateModal(text string, confirm func(), cancel func(), finally func()) {
-	modal := tview.NewModal()
+	modal := t.styleModal(tview.NewModal())
 	t.pages.AddPage("modal", modal.SetText(text).AddButtons([]string{"Confirm", "Cancel"}).SetDoneFunc(func(i int, label string) {
 		if label == "Confirm" {
 			confirm()
@@ -201,28 +324,117 @@ func (t *Tui) CreateModal(text string, confirm func(), cancel func(), finally fu
 	}), true, true)
 }
 
+// confirmChange runs confirm once confirmed in a modal, unless the
+// confirmation policy does not ask for it for this kind of change.
+func (t *Tui) confirmChange(text string, destructive bool, confirm func(), cancel func(), finally func()) {
+	if t.confirm == ConfirmNever || (t.confirm != ConfirmAlways && !destructive) {
+		confirm()
+		t.ReloadTable()
+		finally()
+		return
+	}
+	t.CreateModal(text, confirm, cancel, finally)
+}
+
+func (t *Tui) CreateMessage(text string, finally func()) {
+	modal := t.styleModal(tview.NewModal())
+	t.pages.AddPage("message", modal.SetText(text).AddButtons([]string{"OK"}).SetDoneFunc(func(i int, label string) {
+		t.pages.RemovePage("message")
+		finally()
+	}), true, true)
+}
+
+// ShowUfwError tells why ufw refused a command. Once acknowledged, the form
+// field the error most likely relates to is focused.
+func (t *Tui) ShowUfwError(command string, trace string) {
+	field := utils.ErrorField(trace)
+	index := t.formFieldIndex(field)
+	if index == -1 {
+		field = ""
+	}
+
+	t.CreateMessage(tview.Escape(ufwErrorMessage(command, trace, field)), func() {
+		if t.form.GetFormItemCount() == 0 {
+			t.app.SetFocus(t.table)
+			return
+		}
+		if index != -1 {
+			t.form.SetFocus(index)
+		}
+		t.app.SetFocus(t.form)
+	})
+}
+
+// ufwErrorMessage describes a command refused by ufw, pointing at the form
+// field at fault unless it is empty.
+func ufwErrorMessage(command string, trace string, field string) string {
+	text := fmt.Sprintf("ufw refused the rule:\n\n%s\n\n$ %s", strings.TrimSpace(trace), command)
+	if field != "" {
+		text += fmt.Sprintf("\n\nCheck the %s field.", field)
+	}
+	return text
+}
+
+// formFieldIndex returns the index of a form field, whatever marks its label.
+// Interface stands for either of the interface fields.
+func (t *Tui) formFieldIndex(field string) int {
+	if field == "" {
+		return -1
+	}
+	for i := 0; i < t.form.GetFormItemCount(); i++ {
+		label := strings.TrimSuffix(strings.TrimSpace(t.form.GetFormItem(i).GetLabel()), " *")
+		if label == field || (field == "Interface" && strings.HasPrefix(label, field)) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (t *Tui) SearchForm() {
-	t.form.AddInputField("Regex", "", 20, nil, nil).SetFieldTextColor(tcell.ColorWhite).AddButton("Search", func() {
-		needle := t.form.GetFormItem(0).(*tview.InputField).GetText()
-		data, _ := t.LoadSearchData(needle)
+	rows, _ := t.LoadUFWOutput()
 
-		if len(data) > 0 {
-			t.table.Clear()
-			t.CreateTable(data)
-		} else {
-			t.secondHelp.SetText(" No result.")
+	t.form.AddInputField("Query", "", 40, nil, func(query string) {
+		t.search(rows, query)
+	}).AddButton("Search", func() {
+		query := t.form.GetFormItem(0).(*tview.InputField).GetText()
+		if t.search(rows, query) {
+			t.app.SetFocus(t.table)
 		}
 	}).AddButton("Cancel", func() {
 		t.Reset()
 		t.ReloadTable()
 		t.app.SetFocus(t.menu)
 	})
+
+	t.secondHelp.SetText(searchHelp).SetTextColor(t.theme.Accent).SetBorderPadding(0, 0, 1, 1)
+}
+
+const searchHelp = "Filter on port: to: from: action: dir: iface: proto: comment:\nCombine with AND, OR, NOT and ( ), use field:~regex for patterns"
+
+// search filters the table with query, leaving it untouched while the query
+// is incomplete or invalid.
+func (t *Tui) search(rows []string, query string) bool {
+	matches, err := searchRows(rows, query)
+	if err != nil {
+		t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+		return false
+	}
+
+	t.table.Clear()
+	t.CreateTable(matches)
+	if len(matches) == 0 {
+		t.secondHelp.SetText(" No result.").SetTextColor(t.theme.Accent)
+		return false
+	}
+	t.secondHelp.SetText(searchHelp).SetTextColor(t.theme.Accent)
+
+	return true
 }
 
 func updateInterfaces(interfaces []string, selectedIn string) []string {
 	var filtered []string
 	for _, iface := range interfaces {
-		if iface != selectedIn {
+		if iface == "" || iface != selectedIn {
 			filtered = append(filtered, iface)
 		}
 	}
@@ -231,75 +443,80 @@ func updateInterfaces(interfaces []string, selectedIn string) []string {
 }
 
 func (t *Tui) CreateForm() {
-	t.help.SetText("Use <Tab> and <Enter> keys to navigate through the form").SetBorderPadding(1, 0, 1, 1)
-	interfaces, _ := t.LoadInterfaces()
-
-	var ifaceInDropDown, ifaceOutDropDown *tview.DropDown
-
-	updateInterfaceDropDowns := func(changed string, selected string) {
-		// Update the opposite dropdown based on selection
-		switch changed {
-		case "Interface":
-			filtered := updateInterfaces(interfaces, selected)
-			ifaceOutDropDown.SetOptions(filtered, nil)
-		case "Interface out":
-			filtered := updateInterfaces(interfaces, selected)
-			ifaceInDropDown.SetOptions(filtered, nil)
-		}
-	}
+	t.createForm(domain.FormValues{Action: t.defaultAction})
+}
 
-	ifaceInDropDown = tview.NewDropDown().
-		SetLabel("Interface").
-		SetOptions(interfaces, func(text string, index int) {
-			updateInterfaceDropDowns("Interface", text)
-		})
+// createForm shows the form adding a rule, filled with fv.
+func (t *Tui) createForm(fv domain.FormValues) {
+	t.formHelp()
+	interfaces, _ := t.LoadInterfaces()
 
-	ifaceOutDropDown = tview.NewDropDown().
-		SetLabel("Interface out").
-		SetOptions(interfaces, func(text string, index int) {
-			updateInterfaceDropDowns("Interface out", text)
-		})
+	ifaceInDropDown, ifaceOutDropDown := interfaceDropDowns(interfaces, fv.Interface, fv.InterfaceOut)
 
-	t.form.AddInputField("To", "", 20, nil, nil).SetFieldTextColor(tcell.ColorWhite).
-		AddInputField("Port", "", 20, utils.ValidatePort, nil).SetFieldTextColor(tcell.ColorWhite).
-		AddDropDown("Action *", []string{"ALLOW IN", "DENY IN", "REJECT IN", "LIMIT IN", "ALLOW OUT", "DENY OUT", "REJECT OUT", "LIMIT OUT", "ALLOW FWD", "DENY FWD"}, 0, func(action string, index int) {
-			if action == "ALLOW FWD" || action == "DENY FWD" {
-				// Ensure the Interface out dropdown is in the form
-				found := false
-				for i := 0; i < t.form.GetFormItemCount(); i++ {
-					if t.form.GetFormItem(i).GetLabel() == "Interface out" {
-						found = true
-						break
-					}
-				}
-				if !found {
-					t.form.AddFormItem(ifaceOutDropDown)
-				}
-			} else {
-				// Remove Interface out if it exists
-				for i := 0; i < t.form.GetFormItemCount(); i++ {
-					if t.form.GetFormItem(i).GetLabel() == "Interface out" {
-						t.form.RemoveFormItem(i)
-						break
-					}
-				}
-			}
-		}).
+	t.form.AddInputField("To", fv.To, 20, nil, nil).
+		AddFormItem(t.portField(fv.Port)).
+		AddDropDown("Action *", actions, max(slices.Index(actions, fv.Action), 0), nil).
 		AddFormItem(ifaceInDropDown).
-		AddDropDown("Protocol", []string{"", "tcp", "udp"}, 0, nil).
-		AddInputField("From", "", 20, nil, nil).
-		AddInputField("Comment", "", 40, nil, nil).
+		AddDropDown("Protocol", protocols, max(slices.Index(protocols, fv.Protocol), 0), nil).
+		AddInputField("From", fv.From, 20, nil, nil).
+		AddInputField("Comment", fv.Comment, 40, nil, nil).
 		AddButton("Save", func() { t.CreateRule() }).
 		AddButton("Cancel", func() {
 			t.Reset()
 			t.app.SetFocus(t.menu)
-		}).
-		SetButtonTextColor(tcell.ColorWhite).
-		SetButtonBackgroundColor(t.color).
-		SetFieldBackgroundColor(t.color).
-		SetLabelColor(tcell.ColorWhite)
+		})
+
+	actionDropDown := t.form.GetFormItemByLabel("Action *").(*tview.DropDown)
+	actionDropDown.SetSelectedFunc(func(action string, index int) {
+		t.toggleInterfaces(action, ifaceInDropDown, ifaceOutDropDown)
+	})
+	_, action := actionDropDown.GetCurrentOption()
+	t.toggleInterfaces(action, ifaceInDropDown, ifaceOutDropDown)
+
+	t.secondHelp.SetText(ruleFormHelp).SetTextColor(t.theme.Accent).SetBorderPadding(0, 0, 1, 1)
+	t.watchRuleForm()
+}
+
+// interfaceDropDowns creates the incoming and outgoing interface dropdowns,
+// which cannot both select the same interface.
+func interfaceDropDowns(interfaces []string, ifaceIn string, ifaceOut string) (*tview.DropDown, *tview.DropDown) {
+	ifaceInDropDown := tview.NewDropDown().SetLabel("Interface")
+	ifaceOutDropDown := tview.NewDropDown().SetLabel("Interface out")
+
+	var onIn, onOut func(text string, index int)
+	exclude := func(dropDown *tview.DropDown, selected string, handler func(text string, index int)) {
+		_, current := dropDown.GetCurrentOption()
+		options := updateInterfaces(interfaces, selected)
+		// Detach the handler while restoring the selection to avoid ping-pong updates
+		dropDown.SetOptions(options, nil)
+		dropDown.SetCurrentOption(utils.ParseInterfaceIndex(current, options))
+		dropDown.SetSelectedFunc(handler)
+	}
+	onIn = func(text string, index int) { exclude(ifaceOutDropDown, text, onOut) }
+	onOut = func(text string, index int) { exclude(ifaceInDropDown, text, onIn) }
 
-	t.secondHelp.SetText("* Mandatory field\n\nPort, To and From fields respectively match any and Anywhere if left empty").SetTextColor(t.color).SetBorderPadding(0, 0, 1, 1)
+	ifaceInDropDown.SetOptions(interfaces, nil).SetCurrentOption(utils.ParseInterfaceIndex(ifaceIn, interfaces))
+	ifaceOutDropDown.SetOptions(interfaces, nil).SetCurrentOption(utils.ParseInterfaceIndex(ifaceOut, interfaces))
+	onIn(ifaceIn, 0)
+	onOut(ifaceOut, 0)
+
+	return ifaceInDropDown, ifaceOutDropDown
+}
+
+// toggleInterfaces shows the interface dropdowns matching the direction of
+// the action: incoming for IN rules, outgoing for OUT rules and both for routed ones.
+func (t *Tui) toggleInterfaces(action string, ifaceIn *tview.DropDown, ifaceOut *tview.DropDown) {
+	t.toggleFormItem(ifaceIn, !strings.HasSuffix(action, "OUT"))
+	t.toggleFormItem(ifaceOut, !strings.HasSuffix(action, "IN"))
+}
+
+func (t *Tui) toggleFormItem(item tview.FormItem, visible bool) {
+	index := t.form.GetFormItemIndex(item.GetLabel())
+	if visible && index == -1 {
+		t.form.AddFormItem(item)
+	} else if !visible && index != -1 {
+		t.form.RemoveFormItem(index)
+	}
 }
 
 func (t *Tui) ParseFormValues() domain.FormValues {
@@ -360,6 +577,11 @@ func (t *Tui) ParseFormValues() domain.FormValues {
 		}
 	}
 
+	// Service names are given to ufw as ports
+	if port, proto, err := t.services.Resolve(fv.Port, fv.Protocol); err == nil {
+		fv.Port, fv.Protocol = port, proto
+	}
+
 	return fv
 }
 
@@ -369,148 +591,121 @@ func (t *Tui) EditForm() {
 			t.app.SetFocus(t.table)
 			return
 		}
-		t.help.SetText("Use <Tab> and <Enter> keys to navigate through the form").SetBorderPadding(1, 0, 1, 1)
-		interfaces, _ := t.LoadInterfaces()
-
-		toCell := t.table.GetCell(row, 1).Text
-		fromCell := t.table.GetCell(row, 4).Text
-
-		toValue, proto, ninterfaceOut := utils.ParseFromOrTo(toCell)
-		fromValue, _, ninterface := utils.ParseFromOrTo(fromCell)
-		interfaceOptionIndex := utils.ParseInterfaceIndex(ninterface, interfaces)
-
-		protocolOptionIndex := 0
-		switch proto {
-		case "tcp":
-			protocolOptionIndex = 1
-		case "udp":
-			protocolOptionIndex = 2
-		default:
-			protocolOptionIndex = 0
-		}
-
-		portValue := utils.ParsePort(t.table.GetCell(row, 2).Text)
-
-		actionText := t.table.GetCell(row, 3).Text
-
-		actionOptionIndex := 0
-		switch actionText {
-		case "ALLOW-IN":
-			actionOptionIndex = 0
-		case "DENY-IN":
-			actionOptionIndex = 1
-		case "REJECT-IN":
-			actionOptionIndex = 2
-		case "LIMIT-IN":
-			actionOptionIndex = 3
-		case "ALLOW-OUT":
-			actionOptionIndex = 4
-		case "DENY-OUT":
-			actionOptionIndex = 5
-		case "REJECT-OUT":
-			actionOptionIndex = 6
-		case "LIMIT-OUT":
-			actionOptionIndex = 7
-		case "ALLOW-FWD":
-			actionOptionIndex = 8
-		case "DENY-FWD":
-			actionOptionIndex = 9
-		}
-
-		comment := strings.ReplaceAll(t.table.GetCell(row, 5).Text, "# ", "")
-
-		var ifaceInDropDown, ifaceOutDropDown *tview.DropDown
-		selectedIface := interfaces[interfaceOptionIndex]
-		outInterfaces := updateInterfaces(interfaces, selectedIface)
-
-		outInterfaceIndex := 0
-
-		if actionText == "ALLOW-FWD" || actionText == "DENY-FWD" {
-			outInterfaceIndex = utils.ParseInterfaceIndex(ninterfaceOut, outInterfaces)
-		}
-
-		ifaceInDropDown = tview.NewDropDown().
-			SetLabel("Interface").
-			SetOptions(interfaces, nil).
-			SetCurrentOption(interfaceOptionIndex)
-
-		ifaceOutDropDown = tview.NewDropDown().
-			SetLabel("Interface out").
-			SetOptions(outInterfaces, nil).
-			SetCurrentOption(outInterfaceIndex)
-
-		// --- Mutual exclusion logic
-		ifaceInDropDown.SetSelectedFunc(func(text string, index int) {
-			ifaceOutDropDown.SetOptions(updateInterfaces(interfaces, text), nil)
-		})
-		ifaceOutDropDown.SetSelectedFunc(func(text string, index int) {
-			ifaceInDropDown.SetOptions(updateInterfaces(interfaces, text), nil)
-		})
+		t.editRow(row)
+	})
+}
 
-		// --- Show/hide Interface out
-		showOrRemoveInterfaceOut := func(action string) {
-			found := false
-			for i := 0; i < t.form.GetFormItemCount(); i++ {
-				if t.form.GetFormItem(i).GetLabel() == "Interface out" {
-					found = true
-					break
-				}
-			}
+// rowFormValues returns the form values of the rule displayed on a table row.
+func (t *Tui) rowFormValues(row int) domain.FormValues {
+	cellValues := t.rows[row-1]
 
-			if action == "ALLOW FWD" || action == "DENY FWD" {
-				if !found {
-					t.form.AddFormItem(ifaceOutDropDown)
-				}
-			} else if found {
-				for i := 0; i < t.form.GetFormItemCount(); i++ {
-					if t.form.GetFormItem(i).GetLabel() == "Interface out" {
-						t.form.RemoveFormItem(i)
-						break
-					}
-				}
-			}
-		}
+	toValue, proto, _ := utils.ParseFromOrTo(cellValues.To)
+	fromValue, _, _ := utils.ParseFromOrTo(cellValues.From)
+	ninterface, ninterfaceOut := utils.ParseInterfaces(cellValues.Interface)
 
-		t.form.AddInputField("To", toValue, 20, nil, nil).SetFieldTextColor(tcell.ColorWhite).
-			AddInputField("Port", portValue, 20, utils.ValidatePort, nil).SetFieldTextColor(tcell.ColorWhite).
-			AddDropDown("Action *", []string{"ALLOW IN", "DENY IN", "REJECT IN", "LIMIT IN", "ALLOW OUT", "DENY OUT", "REJECT OUT", "LIMIT OUT", "ALLOW FWD", "DENY FWD"}, actionOptionIndex, func(action string, index int) {
-				showOrRemoveInterfaceOut(action)
-			}).
-			AddDropDown("Interface", interfaces, interfaceOptionIndex, nil).
-			AddDropDown("Protocol", []string{"", "tcp", "udp"}, protocolOptionIndex, nil).
-			AddInputField("From", fromValue, 20, nil, nil).
-			AddInputField("Comment", comment, 40, nil, nil)
+	// Left empty, To and From match Anywhere
+	if toValue == "Anywhere" {
+		toValue = ""
+	}
+	if fromValue == "Anywhere" {
+		fromValue = ""
+	}
 
-		t.form.AddButton("Save", func() {
-			editObject := t.ParseFormValues()
-			t.EditRule(row, editObject)
-			t.app.SetFocus(t.table)
-		}).
-			AddButton("Cancel", func() {
-				t.Reset()
-				t.help.SetText("Press <Esc> to go back to the menu selection").SetBorderPadding(1, 0, 1, 0)
-				t.app.SetFocus(t.table)
-			}).
-			SetButtonTextColor(tcell.ColorWhite).
-			SetButtonBackgroundColor(t.color).
-			SetFieldBackgroundColor(t.color).
-			SetLabelColor(tcell.ColorWhite)
+	return domain.FormValues{
+		To:           toValue,
+		Port:         portFromCell(cellValues.Port),
+		Interface:    ninterface,
+		InterfaceOut: ninterfaceOut,
+		Protocol:     proto,
+		Action:       strings.ReplaceAll(cellValues.Action, "-", " "),
+		From:         fromValue,
+		Comment:      strings.ReplaceAll(cellValues.Comment, "# ", ""),
+	}
+}
 
-		t.secondHelp.SetText("* Mandatory field\n\nPort, To and From fields respectively match any and Anywhere if left empty").
-			SetTextColor(t.color).
-			SetBorderPadding(0, 0, 1, 1)
+// editRow shows the form editing the rule displayed on a table row.
+func (t *Tui) editRow(row int) {
+	t.formHelp()
+	interfaces, _ := t.LoadInterfaces()
 
-		t.app.SetFocus(t.form)
+	fv := t.rowFormValues(row)
+	actionOptionIndex := max(slices.Index(actions, fv.Action), 0)
+
+	ifaceInDropDown, ifaceOutDropDown := interfaceDropDowns(interfaces, fv.Interface, fv.InterfaceOut)
+
+	t.form.AddInputField("To", fv.To, 20, nil, nil).
+		AddFormItem(t.portField(fv.Port)).
+		AddDropDown("Action *", actions, actionOptionIndex, nil).
+		AddFormItem(ifaceInDropDown).
+		AddDropDown("Protocol", protocols, max(slices.Index(protocols, fv.Protocol), 0), nil).
+		AddInputField("From", fv.From, 20, nil, nil).
+		AddInputField("Comment", fv.Comment, 40, nil, nil)
+
+	actionDropDown := t.form.GetFormItemByLabel("Action *").(*tview.DropDown)
+	actionDropDown.SetSelectedFunc(func(action string, index int) {
+		t.toggleInterfaces(action, ifaceInDropDown, ifaceOutDropDown)
 	})
+	t.toggleInterfaces(actions[actionOptionIndex], ifaceInDropDown, ifaceOutDropDown)
+
+	t.form.AddButton("Save", func() {
+		if !t.validateRuleForm() {
+			return
+		}
+		editObject := t.ParseFormValues()
+		t.EditRule(t.RuleNumber(row), editObject)
+	}).
+		AddButton("Cancel", func() {
+			t.Reset()
+			t.tableHelp()
+			t.app.SetFocus(t.table)
+		})
+
+	t.secondHelp.SetText(ruleFormHelp).
+		SetTextColor(t.theme.Accent).
+		SetBorderPadding(0, 0, 1, 1)
+	t.watchRuleForm()
+
+	t.app.SetFocus(t.form)
+}
+
+// actionCmd builds the action part of a rule, e.g. "allow in on eth0",
+// "deny out on wg0" or "allow in on eth0 out on eth1" for a route.
+func actionCmd(action string, ifaceIn string, ifaceOut string) string {
+	tokens := strings.Fields(strings.ToLower(strings.ReplaceAll(action, "-", " ")))
+	if len(tokens) < 2 {
+		return strings.Join(tokens, " ")
+	}
+
+	cmd := tokens[0]
+	switch tokens[1] {
+	case "fwd":
+		if ifaceIn != "" {
+			cmd = fmt.Sprintf("%s in on %s", cmd, ifaceIn)
+		}
+		if ifaceOut != "" {
+			cmd = fmt.Sprintf("%s out on %s", cmd, ifaceOut)
+		}
+	case "out":
+		cmd += " out"
+		if ifaceOut != "" {
+			cmd = fmt.Sprintf("%s on %s", cmd, ifaceOut)
+		}
+	default:
+		cmd += " " + tokens[1]
+		if ifaceIn != "" {
+			cmd = fmt.Sprintf("%s on %s", cmd, ifaceIn)
+		}
+	}
+	return cmd
 }
 
 func (t *Tui) EditRule(position int, object domain.FormValues, rows ...int) *string {
-	if object.Port == "" && object.Protocol == "" && object.Interface == "" && object.To == "" && object.From == "" {
+	if object.Port == "" && object.Protocol == "" && object.Interface == "" && object.InterfaceOut == "" && object.To == "" && object.From == "" {
 		return nil
 	}
 
 	// For testing purposes (easy mock)
-	rowCount := t.table.GetRowCount()
+	rowCount := t.ruleCount + 1
 	if len(rows) > 0 {
 		rowCount = rows[0]
 	}
@@ -522,19 +717,8 @@ func (t *Tui) EditRule(position int, object domain.FormValues, rows ...int) *str
 		object.From = "any"
 	}
 
-	preCmd := strings.ToLower(strings.ReplaceAll(object.Action, "-", " "))
-	if object.Interface != "" {
-		preCmd = fmt.Sprintf("%s on %s", preCmd, object.Interface)
-	}
-
-	if strings.Contains(strings.ToLower(object.Action), "fwd") {
-		tokens := strings.Split(object.Action, "-")
-		if object.InterfaceOut != "" {
-			preCmd = fmt.Sprintf("%s in on %s out on %s", strings.ToLower(tokens[0]), object.Interface, object.InterfaceOut)
-		} else {
-			preCmd = fmt.Sprintf("%s in on %s", strings.ToLower(tokens[0]), object.Interface)
-		}
-	}
+	preCmd := actionCmd(object.Action, object.Interface, object.InterfaceOut)
+	fwd := strings.Contains(strings.ToLower(object.Action), "fwd")
 
 	var parts []string
 	parts = append(parts, "from", object.From, "to", object.To)
@@ -553,134 +737,386 @@ func (t *Tui) EditRule(position int, object domain.FormValues, rows ...int) *str
 
 	cmd := strings.Join(parts, " ")
 
-	dryCmd := fmt.Sprintf("ufw --dry-run %s %s", preCmd, cmd)
-	baseCmd := fmt.Sprintf("ufw %s %s", preCmd, cmd)
-	if position < rowCount-1 {
-		dryCmd = fmt.Sprintf("ufw --dry-run insert %d %s %s", position, preCmd, cmd)
-		baseCmd = fmt.Sprintf("ufw insert %d %s %s", position, preCmd, cmd)
+	prefix := ""
+	if fwd {
+		prefix = "route "
+	}
 
-		if strings.Contains(strings.ToLower(object.Action), "fwd") {
-			dryCmd = fmt.Sprintf("ufw --dry-run route insert %d %s %s", position, preCmd, cmd)
-			baseCmd = fmt.Sprintf("ufw route insert %d %s %s", position, preCmd, cmd)
-		}
+	dryCmd := fmt.Sprintf("ufw --dry-run %s%s %s", prefix, preCmd, cmd)
+	baseCmd := fmt.Sprintf("ufw %s%s %s", prefix, preCmd, cmd)
+	if position < rowCount-1 {
+		dryCmd = fmt.Sprintf("ufw --dry-run %sinsert %d %s %s", prefix, position, preCmd, cmd)
+		baseCmd = fmt.Sprintf("ufw %sinsert %d %s %s", prefix, position, preCmd, cmd)
 	}
 
 	_, trace, err := shellout(dryCmd)
-	if err == nil {
-		// If replacing, delete first
-		if _, trace, err := shellout(fmt.Sprintf("ufw --force delete %d", position)); err != nil {
-			log.Printf("Failed to delete previous rule: %s", trace)
-			return nil
-		}
-
-		// Apply rule
-		if _, trace, err := shellout(baseCmd); err != nil {
-			log.Printf("Failed to apply rule: %s - %s", trace, baseCmd)
-			return nil
-		}
-		log.Printf("Editing rule: %s", baseCmd)
-	} else {
+	if err != nil {
 		log.Printf("Invalid rule: %s - %s", trace, dryCmd)
+		t.ShowUfwError(dryCmd, trace)
 		return nil
 	}
 
-	t.Reset()
-	t.ReloadTable()
+	applied := false
+	t.ProtectSession(func() []domain.Rule {
+		return utils.SpliceRules(t.LoadRules(), []int{position}, utils.RuleFromForm(object))
+	}, func() {
+		t.confirmChange(fmt.Sprintf("Replace rule %d?\n\n%s", position, baseCmd), false, func() {
+			t.SafeApply(func() {
+				// If replacing, delete first
+				if _, trace, err := shellout(fmt.Sprintf("ufw --force delete %d", position)); err != nil {
+					log.Printf("Failed to delete previous rule: %s", trace)
+					t.ShowUfwError(fmt.Sprintf("ufw --force delete %d", position), trace)
+					return
+				}
+
+				// Apply rule
+				if _, trace, err := shellout(baseCmd); err != nil {
+					log.Printf("Failed to apply rule: %s - %s", trace, baseCmd)
+					t.ShowUfwError(baseCmd, trace)
+					return
+				}
+				log.Printf("Editing rule: %s", baseCmd)
+				applied = true
+
+				t.Reset()
+				t.ReloadTable()
+				t.app.SetFocus(t.table)
+			})
+		}, func() {
+			t.app.SetFocus(t.form)
+		}, func() {
+			t.pages.RemovePage("modal")
+		})
+	}, func() {
+		t.app.SetFocus(t.form)
+	})
+
+	if !applied {
+		return nil
+	}
 	return &baseCmd
 }
 
+// ruleCommand builds the ufw arguments adding the rule, inserting it at
+// position unless it is 0.
+func ruleCommand(fv domain.FormValues, position int) string {
+	to, from := fv.To, fv.From
+	if to == "" {
+		to = "any"
+	}
+	if from == "" {
+		from = "any"
+	}
+
+	// Build the preCmd part
+	preCmd := actionCmd(fv.Action, fv.Interface, fv.InterfaceOut)
+	if position > 0 {
+		preCmd = fmt.Sprintf("insert %d %s", position, preCmd)
+	}
+	if strings.Contains(strings.ToUpper(fv.Action), "FWD") {
+		preCmd = "route " + preCmd
+	}
+
+	// Build the main rule parts
+	var parts []string
+	parts = append(parts, "from", from, "to", to)
+
+	if fv.Protocol != "" {
+		parts = append(parts, "proto", fv.Protocol)
+	}
+	if fv.Port != "" {
+		parts = append(parts, "port", fv.Port)
+	}
+	if fv.Comment != "" {
+		// Escape single quotes inside comment
+		escaped := strings.ReplaceAll(fv.Comment, "'", "''")
+		parts = append(parts, "comment", fmt.Sprintf("'%s'", escaped))
+	}
+
+	return preCmd + " " + strings.Join(parts, " ")
+}
+
 func (t *Tui) CreateRule() {
-	var ninterfaceOut string
+	var ninterface, ninterfaceOut string
+	if item, ok := t.form.GetFormItemByLabel("Interface").(*tview.DropDown); ok {
+		_, ninterface = item.GetCurrentOption()
+	}
 	if item, ok := t.form.GetFormItemByLabel("Interface out").(*tview.DropDown); ok {
 		_, ninterfaceOut = item.GetCurrentOption()
 	}
 
 	to := t.form.GetFormItemByLabel("To").(*tview.InputField).GetText()
 	port := t.form.GetFormItemByLabel("Port").(*tview.InputField).GetText()
-	_, ninterface := t.form.GetFormItemByLabel("Interface").(*tview.DropDown).GetCurrentOption()
 	_, proto := t.form.GetFormItemByLabel("Protocol").(*tview.DropDown).GetCurrentOption()
 	_, action := t.form.GetFormItemByLabel("Action *").(*tview.DropDown).GetCurrentOption()
 	from := t.form.GetFormItemByLabel("From").(*tview.InputField).GetText()
 	comment := t.form.GetFormItemByLabel("Comment").(*tview.InputField).GetText()
 
 	// Guard clauses: no-op if everything is empty
-	if port == "" && proto == "" && ninterface == "" && to == "" && from == "" {
+	if port == "" && proto == "" && ninterface == "" && ninterfaceOut == "" && to == "" && from == "" {
+		return
+	}
+	if !t.validateRuleForm() {
 		return
 	}
 
-	// Default "any" when empty
-	if to == "" {
-		to = "any"
+	var err error
+	fv := domain.FormValues{
+		To:           to,
+		Port:         port,
+		Interface:    ninterface,
+		InterfaceOut: ninterfaceOut,
+		Protocol:     proto,
+		Action:       action,
+		From:         from,
+		Comment:      comment,
 	}
-	if from == "" {
-		from = "any"
+	fv.Port, fv.Protocol, err = t.services.Resolve(port, proto)
+	if err != nil {
+		t.secondHelp.SetText(" " + err.Error()).SetTextColor(t.theme.Error)
+		return
 	}
 
-	// Build the preCmd part
-	preCmd := strings.ToLower(action)
-	if ninterface != "" {
-		preCmd = fmt.Sprintf("%s on %s", preCmd, ninterface)
+	if utils.IsGroup(to) || utils.IsGroup(from) {
+		t.CreateGroupRules(fv)
+		return
 	}
 
-	if strings.Contains(action, "FWD") {
-		tokens := strings.Split(action, " ")
-		if ninterfaceOut != "" {
-			preCmd = fmt.Sprintf("route %s in on %s out on %s", strings.ToLower(tokens[0]), ninterface, ninterfaceOut)
-		} else {
-			preCmd = fmt.Sprintf("route %s in on %s", strings.ToLower(tokens[0]), ninterface)
+	// Choose dry-run or actual
+	dryCmd := "ufw --dry-run " + ruleCommand(fv, 0)
+	baseCmd := "ufw " + ruleCommand(fv, 0)
+
+	// Run dry-run first
+	_, trace, err := shellout(dryCmd)
+	if err != nil {
+		log.Printf("Invalid rule: %s - %s", trace, dryCmd)
+		t.ShowUfwError(dryCmd, trace)
+		return
+	}
+
+	t.ProtectSession(func() []domain.Rule {
+		return utils.SpliceRules(t.LoadRules(), nil, utils.RuleFromForm(fv))
+	}, func() {
+		t.confirmChange("Add this rule?\n\n"+baseCmd, false, func() {
+			t.SafeApply(func() {
+				// Apply rule
+				if _, trace, err := shellout(baseCmd); err != nil {
+					log.Printf("Failed to apply rule: %s - %s", trace, baseCmd)
+					t.ShowUfwError(baseCmd, trace)
+					return
+				}
+				log.Printf("Creating rule: %s", baseCmd)
+
+				t.Reset()
+				t.ReloadTable()
+			})
+		}, func() {
+			t.app.SetFocus(t.form)
+		}, func() {
+			t.pages.RemovePage("modal")
+		})
+	}, func() {
+		t.app.SetFocus(t.form)
+	})
+}
+
+func indexNumber(index string) int {
+	n, _ := strconv.Atoi(strings.Trim(index, "[] "))
+	return n
+}
+
+// RuleNumber returns the ufw rule number displayed on the given table row.
+func (t *Tui) RuleNumber(row int) int {
+	if n := indexNumber(t.table.GetCell(row, 0).Text); n > 0 {
+		return n
+	}
+	return row
+}
+
+func (t *Tui) markRow(row int, marked bool) {
+	if row == 0 || t.marked == nil {
+		return
+	}
+	if marked {
+		t.marked[t.RuleNumber(row)] = true
+	} else {
+		delete(t.marked, t.RuleNumber(row))
+	}
+
+	background := tcell.ColorDefault
+	if marked {
+		background = t.theme.Accent
+	}
+	for c := 0; c < t.table.GetColumnCount(); c++ {
+		t.emphasize(t.table.GetCell(row, c), background, marked)
+	}
+}
+
+func (t *Tui) ClearMarks() {
+	for row := 1; row < t.table.GetRowCount(); row++ {
+		t.markRow(row, false)
+	}
+	t.marked = nil
+	t.anchor = 0
+}
+
+// RemoveRules deletes the given rule numbers in descending order so that
+// the numbering of the remaining ones does not shift mid-batch.
+func (t *Tui) RemoveRules(numbers []int) (deleted []int, failed map[int]string) {
+	sorted := append([]int(nil), numbers...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	failed = map[int]string{}
+	for _, n := range sorted {
+		if _, trace, err := shellout(fmt.Sprintf("ufw --force delete %d", n)); err != nil {
+			log.Printf("Failed to delete rule %d: %s", n, trace)
+			failed[n] = strings.TrimSpace(trace)
+			continue
 		}
+		log.Printf("Deleted rule %d", n)
+		deleted = append(deleted, n)
 	}
 
-	// Build the main rule parts
-	var parts []string
-	parts = append(parts, "from", from, "to", to)
+	return deleted, failed
+}
+
+func removalSummary(deleted []int, failed map[int]string) string {
+	summary := fmt.Sprintf("%d rule(s) deleted.", len(deleted))
+	if len(failed) > 0 {
+		numbers := make([]int, 0, len(failed))
+		for n := range failed {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
 
-	if proto != "" {
-		parts = append(parts, "proto", proto)
+		summary += fmt.Sprintf("\n%d failed:", len(failed))
+		for _, n := range numbers {
+			summary += fmt.Sprintf("\n[%d] %s", n, failed[n])
+		}
 	}
-	if port != "" {
-		parts = append(parts, "port", port)
+	return summary
+}
+
+// tableActions binds the actions of the Status table: moving, sorting and,
+// while deleting, marking rules.
+func (t *Tui) tableActions() {
+	selected := func() int {
+		row, _ := t.table.GetSelection()
+		return row
 	}
-	if comment != "" {
-		// Escape single quotes inside comment
-		escaped := strings.ReplaceAll(comment, "'", "''")
-		parts = append(parts, "comment", fmt.Sprintf("'%s'", escaped))
+	move := func(row int) {
+		t.table.Select(max(1, min(row, t.table.GetRowCount()-1)), 0)
+	}
+	halfPage := func() int {
+		_, _, _, height := t.table.GetInnerRect()
+		return max(height/2, 1)
 	}
 
-	cmd := strings.Join(parts, " ")
+	t.registry.On(PaneTable, "down", func() { move(selected() + 1) })
+	t.registry.On(PaneTable, "up", func() { move(selected() - 1) })
+	t.registry.On(PaneTable, "top", func() { move(1) })
+	t.registry.On(PaneTable, "bottom", func() { move(t.table.GetRowCount() - 1) })
+	t.registry.On(PaneTable, "half-page-down", func() { move(selected() + halfPage()) })
+	t.registry.On(PaneTable, "half-page-up", func() { move(selected() - halfPage()) })
 
-	// Choose dry-run or actual
-	dryCmd := "ufw --dry-run " + preCmd + " " + cmd
-	baseCmd := "ufw " + preCmd + " " + cmd
+	t.registry.OnNth(PaneTable, "sort", func(n int) {
+		if n < len(t.displayedColumns()) {
+			t.SortBy(t.displayedColumns()[n])
+		}
+	})
 
-	// Run dry-run first
-	_, trace, err := shellout(dryCmd)
-	if err == nil {
-		// Apply rule
-		if _, trace, err = shellout(baseCmd); err != nil {
-			log.Printf("Failed to apply rule: %s - %s", trace, baseCmd)
+	t.registry.On(PaneTable, "mark", func() {
+		if t.marked != nil {
+			t.markRow(selected(), !t.marked[t.RuleNumber(selected())])
+		}
+	})
+	t.registry.On(PaneTable, "mark-range", func() {
+		if t.marked == nil {
 			return
 		}
-		log.Printf("Creating rule: %s", baseCmd)
-	} else {
-		log.Printf("Invalid rule: %s - %s", trace, dryCmd)
-		return
-	}
+		row := selected()
+		if t.anchor == 0 {
+			t.anchor = row
+			t.markRow(row, true)
+			return
+		}
+		from, to := t.anchor, row
+		if from > to {
+			from, to = to, from
+		}
+		for r := from; r <= to; r++ {
+			t.markRow(r, true)
+		}
+		t.anchor = 0
+	})
 
-	t.Reset()
-	t.ReloadTable()
+	t.registry.On(PaneTable, "back", t.leaveTable)
+	t.registry.On(PaneTable, "help", func() { t.KeysOverlay(PaneTable) })
+}
+
+// tableHelp tells how to leave the Status table and list its keys.
+func (t *Tui) tableHelp() {
+	t.help.SetText(fmt.Sprintf("Press <%s> to go back to the menu selection, <%s> to list the keys",
+		t.registry.Label("back"), t.registry.Label("help"))).SetBorderPadding(1, 0, 1, 0)
+}
+
+// formHelp tells how to navigate the forms and list their keys.
+func (t *Tui) formHelp() {
+	t.help.SetText(fmt.Sprintf("Use <Tab> and <Enter> keys to navigate through the form, <%s> to list the keys",
+		t.registry.Label("form-help"))).SetBorderPadding(1, 0, 1, 1)
+}
+
+func (t *Tui) tableInput(event *tcell.EventKey) *tcell.EventKey {
+	return t.registry.Handle(PaneTable, event)
 }
 
 func (t *Tui) RemoveRule() {
+	t.marked = map[int]bool{}
+	t.anchor = 0
+	t.secondHelp.SetText(fmt.Sprintf("<%s> marks a rule, <%s> marks a range, <Enter> deletes the marked rules",
+		t.registry.Label("mark"), t.registry.Label("mark-range"))).
+		SetTextColor(t.theme.Accent).
+		SetBorderPadding(0, 0, 1, 1)
+
 	t.table.SetSelectedFunc(func(row int, column int) {
-		t.table.SetSelectable(false, false)
 		if row == 0 {
 			t.app.SetFocus(t.table)
 			return
 		}
-		t.CreateModal("Are you sure you want to remove this rule?",
+
+		numbers := []int{t.RuleNumber(row)}
+		if len(t.marked) > 0 {
+			numbers = numbers[:0]
+			for n := range t.marked {
+				numbers = append(numbers, n)
+			}
+		}
+
+		t.deleteRules(numbers)
+	})
+}
+
+// deleteRules deletes rules once confirmed, summing up the outcome when
+// several rules are deleted or some fail.
+func (t *Tui) deleteRules(numbers []int) {
+	text := "Are you sure you want to remove this rule?"
+	if len(numbers) > 1 {
+		text = fmt.Sprintf("Are you sure you want to remove these %d rules?", len(numbers))
+	}
+
+	t.table.SetSelectable(false, false)
+	summary := ""
+	t.ProtectSession(func() []domain.Rule {
+		return utils.SpliceRules(t.LoadRules(), numbers, nil)
+	}, func() {
+		t.confirmChange(text, true,
 			func() {
-				shellout(fmt.Sprintf("ufw --force delete %d", row))
+				t.SafeApply(func() {
+					deleted, failed := t.RemoveRules(numbers)
+					if len(numbers) > 1 || len(failed) > 0 {
+						summary = removalSummary(deleted, failed)
+					}
+				})
 			},
 			func() {
 				t.pages.HidePage("modal")
@@ -688,66 +1124,304 @@ func (t *Tui) RemoveRule() {
 			},
 			func() {
 				t.pages.HidePage("modal")
+				if summary != "" {
+					t.CreateMessage(summary, func() {
+						t.app.SetFocus(t.table)
+					})
+					return
+				}
 				t.app.SetFocus(t.table)
 			},
 		)
+	}, func() {
+		t.app.SetFocus(t.table)
 	})
 }
 
+func (t *Tui) LoadRules() []domain.Rule {
+	data, _ := t.LoadUFWOutput()
+	return utils.ParseRules(data)
+}
+
+// LoadAddedRules returns the rules ufw will apply once enabled, which
+// `ufw status` does not list while ufw is inactive.
+func (t *Tui) LoadAddedRules() []domain.Rule {
+	out, _, err := shellout("ufw show added")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	var rules []domain.Rule
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "ufw ") {
+			continue
+		}
+		fv, err := utils.ParseUfwCommand(line)
+		if err != nil {
+			log.Printf("Skipping added rule: %v", err)
+			continue
+		}
+		rules = append(rules, utils.RuleFromForm(*fv)...)
+	}
+
+	return rules
+}
+
+func (t *Tui) LoadDefaults() map[string]string {
+	return t.LoadStatus().Defaults
+}
+
+// resolveApps replaces application profiles (e.g. OpenSSH) by the ports they open.
+func (t *Tui) resolveApps(rules []domain.Rule) []domain.Rule {
+	resolved := make([]domain.Rule, len(rules))
+	profiles := map[string][2]string{}
+	for i, rule := range rules {
+		resolved[i] = rule
+		if rule.ToPort == "" || utils.IsPortSpec(rule.ToPort) {
+			continue
+		}
+
+		profile, ok := profiles[rule.ToPort]
+		if !ok {
+			out, _, err := shellout(fmt.Sprintf("ufw app info '%s'", strings.ReplaceAll(rule.ToPort, "'", "")))
+			if err != nil {
+				log.Printf("Unknown application profile %s", rule.ToPort)
+			}
+			ports, proto := utils.ParseAppPorts(out)
+			profile = [2]string{ports, proto}
+			profiles[rule.ToPort] = profile
+		}
+
+		resolved[i].ToPort = profile[0]
+		if profile[1] != "" {
+			resolved[i].Protocol = profile[1]
+		}
+	}
+
+	return resolved
+}
+
+// ProtectSession runs proceed unless the ruleset returned by pending would
+// block the SSH session tufw is running in, in which case the user has to
+// type a confirmation first.
+func (t *Tui) ProtectSession(pending func() []domain.Rule, proceed func(), cancel func()) {
+	if t.session == nil {
+		proceed()
+		return
+	}
+
+	t.protectSessionOf(*t.session, t.resolveApps(pending()), t.LoadDefaults(), proceed, cancel)
+}
+
+// protectSessionOf runs proceed unless rules would block session given the
+// default policies of the host they are on.
+func (t *Tui) protectSessionOf(session domain.Packet, rules []domain.Rule, defaults map[string]string, proceed func(), cancel func()) {
+	action, rule := utils.SimulateSession(rules, defaults, session)
+	if utils.IsAllowed(action) {
+		proceed()
+		return
+	}
+
+	reason := fmt.Sprintf("the default incoming policy (%s)", strings.ToLower(action))
+	if rule != nil {
+		reason = fmt.Sprintf("%q", utils.DescribeRule(*rule))
+	}
+
+	t.CreateTypedConfirmation(
+		fmt.Sprintf("This change would block your SSH session from %s to port %s, which would be matched by %s.",
+			session.From, session.Port, reason),
+		"LOCKOUT", proceed, cancel)
+}
+
+// CreateTypedConfirmation asks the user to type word before running confirm.
+func (t *Tui) CreateTypedConfirmation(text string, word string, confirm func(), cancel func()) {
+	form := tview.NewForm()
+	label := fmt.Sprintf("Type %s to proceed", word)
+
+	form.AddTextView("", text, 0, 3, false, false).
+		AddInputField(label, "", 12, nil, nil).
+		AddButton("Confirm", func() {
+			if form.GetFormItemByLabel(label).(*tview.InputField).GetText() != word {
+				return
+			}
+			t.pages.RemovePage("confirm")
+			confirm()
+		}).
+		AddButton("Cancel", func() {
+			t.pages.RemovePage("confirm")
+			cancel()
+		})
+	t.styleForm(form)
+	if !t.theme.Mono {
+		form.SetButtonBackgroundColor(t.theme.Error).SetFieldBackgroundColor(t.theme.Error)
+	}
+	form.SetBorder(true).SetTitle(" Warning ").SetTitleColor(t.theme.Error)
+
+	grid := tview.NewGrid().
+		SetColumns(0, 70, 0).
+		SetRows(0, 11, 0).
+		AddItem(form, 1, 1, 1, 1, 0, 0, true)
+
+	t.pages.AddPage("confirm", grid, true, true)
+	t.app.SetFocus(form)
+}
+
+func (t *Tui) findingColor(kind string) tcell.Color {
+	switch kind {
+	case utils.FindingShadowed, utils.FindingContradictory:
+		return t.theme.Error
+	default:
+		return t.theme.Warning
+	}
+}
+
+func (t *Tui) ShowDetails(title string, text string) {
+	t.details.SetText(text).SetBorder(true).SetTitle(title)
+	t.side.ResizeItem(t.form, 0, 0)
+	t.side.ResizeItem(t.details, 0, 8)
+}
+
+func (t *Tui) HideDetails() {
+	t.details.Clear()
+	t.side.ResizeItem(t.details, 0, 0)
+	t.side.ResizeItem(t.form, 0, 8)
+}
+
+func (t *Tui) AnalyzeRules() {
+	t.findings = map[int]domain.Finding{}
+	t.ReloadTable()
+
+	if len(t.findings) == 0 {
+		t.ShowDetails(" Analysis ", "No shadowed, redundant or contradictory rule found.")
+		return
+	}
+
+	numbers := make([]int, 0, len(t.findings))
+	for n := range t.findings {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	var lines []string
+	for _, n := range numbers {
+		f := t.findings[n]
+		lines = append(lines, fmt.Sprintf("[%s]%s[-]\n%s\n", t.findingColor(f.Kind).String(), strings.ToUpper(f.Kind), tview.Escape(f.Explanation)))
+	}
+	t.ShowDetails(fmt.Sprintf(" Analysis: %d issue(s) ", len(numbers)), strings.Join(lines, "\n"))
+}
+
 func (t *Tui) CreateMenu() {
 	menuList := tview.NewList()
-	menuList.
-		AddItem("Search a rule", "", '/', func() {
-			t.SearchForm()
-			t.app.SetFocus(t.form)
-			t.help.SetText("Press <Esc> to go back to the menu selection").SetBorderPadding(1, 0, 1, 0)
-		}).
-		AddItem("Add a rule", "", 'a', func() {
-			t.CreateForm()
-			t.app.SetFocus(t.form)
-		}).
-		AddItem("Edit a rule", "", 'e', func() {
-			t.EditForm()
-			t.app.SetFocus(t.table)
-			t.help.SetText("Press <Esc> to go back to the menu selection").SetBorderPadding(1, 0, 1, 0)
-		}).
-		AddItem("Delete a rule", "", 'd', func() {
-			t.RemoveRule()
-			t.app.SetFocus(t.table)
-			t.help.SetText("Press <Esc> to go back to the menu selection").SetBorderPadding(1, 0, 1, 0)
-		}).
-		AddItem("Disable ufw", "", 's', func() {
-			t.CreateModal("Are you sure you want to disable ufw?",
-				func() {
-					shellout("ufw --force disable")
-					t.app.Stop()
-				},
-				func() {
-					t.pages.RemovePage("modal")
-					t.app.SetFocus(t.menu)
-				},
-				func() {
-					t.app.SetFocus(t.menu)
-				},
-			)
-		}).
-		AddItem("Reset rules", "", 'r', func() {
-			t.CreateModal("Are you sure you want to reset all rules?",
-				func() {
-					shellout("ufw --force reset")
-					t.app.Stop()
-				},
-				func() {
-					t.app.SetFocus(t.menu)
-				},
-				func() {
-					t.pages.RemovePage("modal")
-					t.app.SetFocus(t.menu)
-				},
-			)
-		}).
-		AddItem("Exit", "", 'q', func() { t.app.Stop() })
-	menuList.SetShortcutColor(t.color).SetBorderPadding(1, 0, 1, 1)
+	// Entries are selected through the registry, which knows about key sequences and special keys
+	addItem := func(name string, label string, selected func()) {
+		index := menuList.GetItemCount()
+		menuList.AddItem(label, "", t.registry.Shortcut(name), selected)
+		t.registry.On(PaneMenu, name, func() {
+			menuList.SetCurrentItem(index)
+			selected()
+		})
+	}
+
+	addItem("search", "Search a rule", func() {
+		t.SearchForm()
+		t.app.SetFocus(t.form)
+		t.help.SetText("Press <Esc> to go back to the menu selection").SetBorderPadding(1, 0, 1, 0)
+	})
+	addItem("affects", "Which rules affect...", func() {
+		t.AffectsForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("templates", "Rule templates", func() {
+		t.TemplateForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("groups", "Address groups", func() {
+		t.GroupsForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("test", "Test traffic", func() {
+		t.TrafficForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("listeners", "Listening services", func() {
+		t.ListenersPanel()
+	})
+	if len(t.hosts) > 0 {
+		addItem("hosts", "Switch host", func() {
+			t.app.SetFocus(t.hostsList)
+		})
+	}
+	addItem("compare", "Compare rules", func() {
+		t.CompareForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("add", "Add a rule", func() {
+		t.CreateForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("edit", "Edit a rule", func() {
+		t.EditForm()
+		t.app.SetFocus(t.table)
+		t.tableHelp()
+	})
+	addItem("delete", "Delete a rule", func() {
+		t.RemoveRule()
+		t.app.SetFocus(t.table)
+		t.tableHelp()
+	})
+	addItem("filter", "Filter rules", func() {
+		t.FilterForm()
+		t.app.SetFocus(t.form)
+	})
+	addItem("analyze", "Analyze rules", func() {
+		t.AnalyzeRules()
+		t.app.SetFocus(t.table)
+		t.tableHelp()
+	})
+	addItem("disable", "Disable ufw", func() {
+		t.confirmChange("Are you sure you want to disable ufw?", true,
+			func() {
+				shellout("ufw --force disable")
+				t.app.Stop()
+			},
+			func() {
+				t.pages.RemovePage("modal")
+				t.app.SetFocus(t.menu)
+			},
+			func() {
+				t.app.SetFocus(t.menu)
+			},
+		)
+	})
+	addItem("reset", "Reset rules", func() {
+		t.confirmChange("Are you sure you want to reset all rules?", true,
+			func() {
+				shellout("ufw --force reset")
+				t.app.Stop()
+			},
+			func() {
+				t.app.SetFocus(t.menu)
+			},
+			func() {
+				t.pages.RemovePage("modal")
+				t.app.SetFocus(t.menu)
+			},
+		)
+	})
+	addItem("quit", "Exit", func() { t.app.Stop() })
+
+	t.registry.On(PaneMenu, "down", func() {
+		menuList.SetCurrentItem((menuList.GetCurrentItem() + 1) % menuList.GetItemCount())
+	})
+	t.registry.On(PaneMenu, "up", func() {
+		menuList.SetCurrentItem((menuList.GetCurrentItem() + menuList.GetItemCount() - 1) % menuList.GetItemCount())
+	})
+	t.registry.On(PaneMenu, "help", func() { t.KeysOverlay(PaneMenu) })
+	menuList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return t.registry.Handle(PaneMenu, event)
+	})
+
+	menuList.SetShortcutColor(t.theme.Accent).SetSelectedStyle(t.selectedStyle()).SetBorderPadding(1, 0, 1, 1)
 	t.menu.AddItem(menuList, 0, 1, true)
 	t.menu.SetBorder(true).SetTitle(" Menu ")
 }
@@ -761,17 +1435,25 @@ func (t *Tui) Reset() {
 
 func (t *Tui) CreateLayout() *tview.Pages {
 	columns := tview.NewFlex().SetDirection(tview.FlexColumn)
+	if len(t.hosts) > 0 {
+		columns.AddItem(t.CreateHosts(), 0, 1, false)
+	}
 
-	base := tview.NewFlex().AddItem(
-		columns.
-			AddItem(t.menu, 0, 2, true).
-			AddItem(t.table, 0, 4, false),
-		0, 1, true,
-	)
-
-	form := columns.AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+	base := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.titleBar, 1, 0, false).
+		AddItem(
+			columns.
+				AddItem(t.menu, 0, 2, true).
+				AddItem(t.table, 0, 4, false),
+			0, 1, true,
+		).
+		AddItem(t.statusBar, 1, 0, false)
+
+	t.details.SetDynamicColors(true).SetWordWrap(true).SetBorderPadding(0, 0, 1, 1)
+	form := columns.AddItem(t.side.SetDirection(tview.FlexRow).
 		AddItem(t.help, 0, 2, false).
 		AddItem(t.form, 0, 8, false).
+		AddItem(t.details, 0, 0, false).
 		AddItem(t.secondHelp, 0, 2, false),
 		0, 3, false,
 	)
@@ -781,8 +1463,30 @@ func (t *Tui) CreateLayout() *tview.Pages {
 	return t.pages
 }
 
+// autoRefresh reloads the Status table periodically so changes made outside
+// tufw show up, unless a form or rules being marked are in the way.
+func (t *Tui) autoRefresh() {
+	ticker := time.NewTicker(t.refreshEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.app.QueueUpdateDraw(func() {
+			if name, _ := t.pages.GetFrontPage(); name != "base" || len(t.marked) > 0 {
+				return
+			}
+			row, column := t.table.GetSelection()
+			t.ReloadTable()
+			if row < t.table.GetRowCount() {
+				t.table.Select(row, column)
+			}
+		})
+	}
+}
+
 func (t *Tui) Build(data []string) {
 	root := t.CreateLayout()
+	t.LoadServices()
+	t.session = t.sshSession()
 
 	status, _, err := shellout(" ufw status | awk -F': ' '/^Status:/ {printf \"%s\", $2}'")
 	if err != nil {
@@ -792,25 +1496,36 @@ func (t *Tui) Build(data []string) {
 
 	if status != "active" {
 		t.pages.HidePage("base")
-		t.CreateModal("ufw is disabled.\nDo you want to enable it?",
-			func() {
-				shellout("ufw --force enable")
-			},
-			func() {
-				t.app.Stop()
-			},
-			func() {
-				t.pages.HidePage("modal")
-				t.pages.ShowPage("base")
-				t.app.SetFocus(t.menu)
-			},
-		)
+		t.ProtectSession(t.LoadAddedRules, func() {
+			t.CreateModal("ufw is disabled.\nDo you want to enable it?",
+				func() {
+					t.SafeApply(func() {
+						shellout("ufw --force enable")
+					})
+				},
+				func() {
+					t.app.Stop()
+				},
+				func() {
+					t.pages.HidePage("modal")
+					t.pages.ShowPage("base")
+					t.app.SetFocus(t.menu)
+				},
+			)
+		}, func() {
+			t.app.Stop()
+		})
 	}
 
 	t.CreateTable(data)
+	t.RefreshStatusBar(data)
 	t.CreateMenu()
 
-	if err := t.app.SetRoot(root, true).EnableMouse(false).Run(); err != nil {
+	if t.refreshEvery > 0 {
+		go t.autoRefresh()
+	}
+
+	if err := t.app.SetRoot(root, true).EnableMouse(t.mouse).Run(); err != nil {
 		panic(err)
 	}
 }
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gdamore/tcell/v2"
@@ -243,6 +246,53 @@ var tests = []struct {
 		formattedRow: "[1] 22 ALLOW-IN Anywhere",
 		expectedCmd:  "ufw allow in from any to any port 22",
 	},
+	{
+		name: "reject route without interfaces",
+		values: domain.FormValues{
+			To:       "10.8.0.0/24",
+			Action:   "REJECT FWD",
+			Protocol: "",
+			From:     "192.168.1.0/24",
+		},
+		row:          "[ 1] 10.8.0.0/24 REJECT FWD 192.168.1.0/24",
+		formattedRow: "[1] 10.8.0.0/24 - REJECT-FWD 192.168.1.0/24",
+		expectedCmd:  "ufw route reject from 192.168.1.0/24 to 10.8.0.0/24",
+	},
+	{
+		name: "limit route with interface out only",
+		values: domain.FormValues{
+			To:           "10.0.0.2",
+			Port:         "22",
+			InterfaceOut: "wg0",
+			Protocol:     "tcp",
+			Action:       "LIMIT FWD",
+		},
+		row:          "[ 1] 10.0.0.2 22/tcp on wg0 LIMIT FWD Anywhere",
+		formattedRow: "[1] 10.0.0.2/tcp_on_wg0 22 LIMIT-FWD Anywhere",
+		expectedCmd:  "ufw route limit out on wg0 from any to 10.0.0.2 proto tcp port 22",
+	},
+	{
+		name: "allow route with interface in only",
+		values: domain.FormValues{
+			Interface: "eth0",
+			Action:    "ALLOW FWD",
+		},
+		row:          "[ 1] Anywhere ALLOW FWD Anywhere on eth0",
+		formattedRow: "[1] Anywhere - ALLOW-FWD Anywhere_on_eth0",
+		expectedCmd:  "ufw route allow in on eth0 from any to any",
+	},
+	{
+		name: "allow outbound on interface",
+		values: domain.FormValues{
+			To:           "1.1.1.1",
+			Port:         "53",
+			InterfaceOut: "eth0",
+			Action:       "ALLOW OUT",
+		},
+		row:          "[ 1] 1.1.1.1 53 ALLOW OUT Anywhere on eth0 (out)",
+		formattedRow: "[1] 1.1.1.1 53 ALLOW-OUT Anywhere_on_eth0",
+		expectedCmd:  "ufw allow out on eth0 from any to 1.1.1.1 port 53",
+	},
 }
 
 func populateForm(f *tview.Form, v domain.FormValues) {
@@ -332,6 +382,11 @@ func TestCreateRule_BuildsCorrectCommands(t *testing.T) {
 			}
 
 			cell = tui.table.GetCell(1, 5)
+			if cell.Text != cellValues.Interface {
+				t.Errorf("expected value for cell Interface: %q, got %q", cellValues.Interface, cell.Text)
+			}
+
+			cell = tui.table.GetCell(1, 6)
 			if cell.Text != cellValues.Comment {
 				t.Errorf("expected value for cell Comment: %q, got %q", cellValues.Comment, cell.Text)
 			}
@@ -390,6 +445,29 @@ func TestEditRule(t *testing.T) {
 			},
 			expectedCmd: "ufw route insert 3 allow in on eth0 from 10.0.0.0/8 to 192.168.50.10",
 		},
+		{
+			name:     "Deny fwd route without interfaces as last rule",
+			position: 5,
+			values: domain.FormValues{
+				To:       "10.8.0.0/24",
+				Protocol: "",
+				Action:   "DENY-FWD",
+				From:     "192.168.1.0/24",
+			},
+			expectedCmd: "ufw route deny from 192.168.1.0/24 to 10.8.0.0/24",
+		},
+		{
+			name:     "Deny outbound on interface",
+			position: 2,
+			values: domain.FormValues{
+				To:           "8.8.8.8",
+				Port:         "53",
+				InterfaceOut: "wg0",
+				Protocol:     "udp",
+				Action:       "DENY-OUT",
+			},
+			expectedCmd: "ufw insert 2 deny out on wg0 from any to 8.8.8.8 proto udp port 53",
+		},
 	}
 
 	tui := CreateApplication(tcell.ColorBlue)
@@ -408,3 +486,244 @@ func TestEditRule(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveRules(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		if cmd == "ufw --force delete 4" {
+			return "", "ERROR: Could not delete rule", errors.New("exit status 1")
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+
+	deleted, failed := tui.RemoveRules([]int{2, 7, 4, 5})
+
+	expected := []string{
+		"ufw --force delete 7",
+		"ufw --force delete 5",
+		"ufw --force delete 4",
+		"ufw --force delete 2",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands %q, got %q", expected, commands)
+	}
+	if !reflect.DeepEqual(deleted, []int{7, 5, 2}) {
+		t.Errorf("expected deleted rules [7 5 2], got %v", deleted)
+	}
+	if failed[4] != "ERROR: Could not delete rule" || len(failed) != 1 {
+		t.Errorf("expected rule 4 to fail, got %v", failed)
+	}
+
+	summary := removalSummary(deleted, failed)
+	if summary != "3 rule(s) deleted.\n1 failed:\n[4] ERROR: Could not delete rule" {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+}
+
+func TestMarkRule_AfterReload(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		if strings.HasPrefix(cmd, "ufw status numbered") {
+			return "[ 1] 22/tcp ALLOW IN Anywhere\n[ 2] 80/tcp ALLOW IN Anywhere", "", nil
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.ReloadTable()
+	tui.RemoveRule()
+
+	// The table is reloaded once rules are deleted, marking has to go on
+	tui.ReloadTable()
+	tui.table.Select(2, 0)
+	tui.table.GetInputCapture()(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone))
+
+	if !reflect.DeepEqual(tui.marked, map[int]bool{2: true}) {
+		t.Errorf("expected rule 2 to be marked, got %v", tui.marked)
+	}
+}
+
+func TestAnalyzeRules_HighlightsFindings(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		return "[ 1] 22/tcp ALLOW IN Anywhere\n[ 2] 22/tcp DENY IN 10.0.0.0/8\n[ 3] 80 ALLOW IN Anywhere\n[ 4] 80 ALLOW IN Anywhere\n", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateLayout()
+	tui.AnalyzeRules()
+
+	expected := map[int]tcell.Color{1: tcell.ColorWhite, 2: tcell.ColorRed, 3: tcell.ColorWhite, 4: tcell.ColorYellow}
+	for row, color := range expected {
+		fg, _, _ := tui.table.GetCell(row, 1).Style.Decompose()
+		if fg != color {
+			t.Errorf("row %d: expected color %s, got %s", row, color, fg)
+		}
+	}
+
+	if text := tui.details.GetText(true); !strings.Contains(text, "SHADOWED") || !strings.Contains(text, "DUPLICATE") {
+		t.Errorf("expected the details panel to explain the findings, got %q", text)
+	}
+}
+
+func TestProtectSession(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	shellout = func(cmd string) (string, string, error) {
+		switch cmd {
+		case "ufw status verbose":
+			return "Status: active\nDefault: deny (incoming), allow (outgoing), disabled (routed)\n", "", nil
+		case "ufw app info 'OpenSSH'":
+			return "Profile: OpenSSH\n\nPort:\n  22/tcp\n", "", nil
+		}
+		return "[ 1] OpenSSH ALLOW IN Anywhere\n[ 2] 80/tcp ALLOW IN Anywhere\n", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateLayout()
+	tui.session = utils.ParseSSHConnection("203.0.113.4 51234 192.168.0.1 22")
+
+	proceeded := false
+	tui.ProtectSession(tui.LoadRules, func() { proceeded = true }, func() {})
+	if !proceeded || tui.pages.HasPage("confirm") {
+		t.Errorf("expected a ruleset allowing SSH to proceed without confirmation")
+	}
+
+	proceeded = false
+	tui.ProtectSession(func() []domain.Rule {
+		return utils.SpliceRules(tui.LoadRules(), []int{1}, nil)
+	}, func() { proceeded = true }, func() {})
+	if proceeded || !tui.pages.HasPage("confirm") {
+		t.Errorf("expected deleting the SSH rule to require a typed confirmation")
+	}
+}
+
+func TestProtectSession_Interface(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	route := ""
+	shellout = func(cmd string) (string, string, error) {
+		switch cmd {
+		case "ufw status verbose":
+			return "Status: active\nDefault: deny (incoming), allow (outgoing), disabled (routed)\n", "", nil
+		case "ip route get 203.0.113.4":
+			if route == "" {
+				return "", "RTNETLINK answers: Operation not permitted", errors.New("exit status 2")
+			}
+			return route, "", nil
+		}
+		return "[ 1] 22 on eth0 DENY IN Anywhere\n[ 2] 22/tcp ALLOW IN Anywhere\n", "", nil
+	}
+
+	for _, tt := range []struct {
+		route   string
+		blocked bool
+	}{
+		// The session may come in through eth0 when its interface is unknown
+		{"", true},
+		{"203.0.113.4 via 192.168.0.254 dev eth0 src 192.168.0.1 uid 0", true},
+		{"203.0.113.4 via 10.0.0.254 dev eth1 src 10.0.0.1 uid 0", false},
+	} {
+		route = tt.route
+		tui := CreateApplication(tcell.ColorBlue)
+		tui.Init()
+		tui.CreateLayout()
+		tui.session = sessionInterface(utils.ParseSSHConnection("203.0.113.4 51234 192.168.0.1 22"), shellout)
+
+		proceeded := false
+		tui.ProtectSession(tui.LoadRules, func() { proceeded = true }, func() {})
+		if proceeded == tt.blocked || tui.pages.HasPage("confirm") != tt.blocked {
+			t.Errorf("route %q: expected blocked to be %v", tt.route, tt.blocked)
+		}
+	}
+}
+
+func TestInterfaceDropDowns(t *testing.T) {
+	interfaces := []string{"", "eth0", "eth1", "wg0"}
+	ifaceIn, ifaceOut := interfaceDropDowns(interfaces, "eth0", "wg0")
+
+	if _, text := ifaceIn.GetCurrentOption(); text != "eth0" {
+		t.Errorf("expected interface in to be eth0, got %q", text)
+	}
+	if _, text := ifaceOut.GetCurrentOption(); text != "wg0" {
+		t.Errorf("expected interface out to be wg0, got %q", text)
+	}
+	if ifaceOut.GetOptionCount() != 3 {
+		t.Errorf("expected eth0 to be excluded from the outgoing interfaces, got %d options", ifaceOut.GetOptionCount())
+	}
+
+	// Changing one side keeps the selection of the other one
+	ifaceIn.SetCurrentOption(2)
+	if _, text := ifaceOut.GetCurrentOption(); text != "wg0" {
+		t.Errorf("expected interface out to be kept, got %q", text)
+	}
+
+	ifaceOut.SetCurrentOption(1)
+	if _, text := ifaceOut.GetCurrentOption(); text != "eth0" {
+		t.Errorf("expected interface out to be eth0, got %q", text)
+	}
+	if _, text := ifaceIn.GetCurrentOption(); text != "eth1" {
+		t.Errorf("expected interface in to be kept, got %q", text)
+	}
+	if ifaceIn.GetOptionCount() != 3 {
+		t.Errorf("expected eth0 to be excluded from the incoming interfaces, got %d options", ifaceIn.GetOptionCount())
+	}
+}
+
+func TestCreateRule_ShowsUfwError(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		if strings.HasPrefix(cmd, "ufw --dry-run") {
+			return "", "ERROR: Bad source address\n", errors.New("exit status 1")
+		}
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateLayout()
+	populateForm(tui.form, domain.FormValues{From: "10.0.0.3", Port: "22", Action: "ALLOW IN"})
+	tui.CreateRule()
+
+	if !tui.pages.HasPage("message") {
+		t.Errorf("expected the error of ufw to be shown")
+	}
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd, "ufw allow") {
+			t.Errorf("the rule should not be applied, got %q", cmd)
+		}
+	}
+
+	field := utils.ErrorField("ERROR: Bad source address")
+	if index := tui.formFieldIndex(field); tui.form.GetFormItem(index).GetLabel() != "From" {
+		t.Errorf("expected the From field to be pointed at, got %q", field)
+	}
+	if tui.formFieldIndex("Action") != 5 || tui.formFieldIndex("Interface") != 2 || tui.formFieldIndex("") != -1 {
+		t.Errorf("unexpected form field indexes")
+	}
+
+	expected := "ufw refused the rule:\n\nERROR: Bad source address\n\n$ ufw --dry-run allow in from 10.0.0.3 to any port 22\n\nCheck the From field."
+	if got := ufwErrorMessage(commands[0], "ERROR: Bad source address\n", field); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/peltho/tufw/internal/core/utils"
+	"github.com/rivo/tview"
+)
+
+const ruleFormHelp = "* Mandatory field\n\nPort, To and From fields respectively match any and Anywhere if left empty"
+
+// watchRuleForm validates the rule form whenever one of its fields changes.
+func (t *Tui) watchRuleForm() {
+	for _, label := range []string{"To", "Port", "From"} {
+		if field, ok := t.form.GetFormItemByLabel(label).(*tview.InputField); ok {
+			field.SetChangedFunc(func(string) { t.validateRuleForm() })
+		}
+	}
+	if protocol, ok := t.form.GetFormItemByLabel("Protocol").(*tview.DropDown); ok {
+		protocol.SetSelectedFunc(func(string, int) { t.validateRuleForm() })
+	}
+}
+
+// validateRuleForm highlights the invalid fields of the rule form and lists
+// what is wrong with them, returning whether the form is valid.
+func (t *Tui) validateRuleForm() bool {
+	errs := utils.ValidateRule(t.ParseFormValues(), t.services)
+
+	invalid := map[string]bool{}
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		invalid[err.Field] = true
+		lines[i] = err.Error()
+	}
+	for _, label := range []string{"To", "Port", "From"} {
+		if field, ok := t.form.GetFormItemByLabel(label).(*tview.InputField); ok {
+			t.flagField(field, invalid[label])
+		}
+	}
+
+	if len(errs) > 0 {
+		t.secondHelp.SetText(strings.Join(lines, "\n")).SetTextColor(t.theme.Error)
+		return false
+	}
+	t.secondHelp.SetText(ruleFormHelp).SetTextColor(t.theme.Accent)
+	return true
+}
+
+// flagField highlights the label of an invalid field. The form sets the
+// color of its labels when drawn, only their background is kept.
+func (t *Tui) flagField(field *tview.InputField, invalid bool) {
+	style := tcell.StyleDefault
+	switch {
+	case invalid && t.theme.Mono:
+		style = style.Underline(true)
+	case invalid:
+		style = style.Background(t.theme.Error)
+	}
+	field.SetLabelStyle(style)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestRuleFormValidation(t *testing.T) {
+	origShellout := shellout
+	defer func() { shellout = origShellout }()
+
+	var commands []string
+	shellout = func(cmd string) (string, string, error) {
+		commands = append(commands, cmd)
+		return "", "", nil
+	}
+
+	tui := CreateApplication(tcell.ColorBlue)
+	tui.Init()
+	tui.CreateLayout()
+	tui.CreateForm()
+
+	labelBackground := func(label string) tcell.Color {
+		_, background, _ := tui.form.GetFormItemByLabel(label).(*tview.InputField).GetLabelStyle().Decompose()
+		return background
+	}
+
+	port := tui.form.GetFormItemByLabel("Port").(*tview.InputField)
+	port.SetText("99999")
+	tui.form.GetFormItemByLabel("From").(*tview.InputField).SetText("10.0.0.0/8")
+
+	if labelBackground("Port") != tui.theme.Error || labelBackground("From") == tui.theme.Error {
+		t.Errorf("only the Port field should be highlighted")
+	}
+	if text := tui.secondHelp.GetText(true); !strings.Contains(text, "Port: port 99999 is not between 1 and 65535") {
+		t.Errorf("unexpected message %q", text)
+	}
+
+	commands = nil
+	tui.CreateRule()
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd, "ufw ") {
+			t.Errorf("an invalid rule should not reach ufw, got %q", cmd)
+		}
+	}
+
+	// Lists of ports need a protocol, which clears the error once selected
+	port.SetText("80,443")
+	if labelBackground("Port") != tui.theme.Error {
+		t.Errorf("a list of ports without protocol should be highlighted")
+	}
+	tui.form.GetFormItemByLabel("Protocol").(*tview.DropDown).SetCurrentOption(1)
+	if labelBackground("Port") == tui.theme.Error || tui.secondHelp.GetText(true) != ruleFormHelp {
+		t.Errorf("the form should be valid, got %q", tui.secondHelp.GetText(true))
+	}
+}
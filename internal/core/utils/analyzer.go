@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+const (
+	FindingDuplicate     = "duplicate"
+	FindingRedundant     = "redundant"
+	FindingShadowed      = "shadowed"
+	FindingContradictory = "contradictory"
+)
+
+// DescribeRule returns a short human readable form of a rule, e.g. "DENY IN 22/tcp from 10.0.0.0/8".
+func DescribeRule(r domain.Rule) string {
+	parts := []string{r.Action, r.Direction}
+
+	to := r.To
+	if r.ToPort != "" {
+		if to == "any" {
+			to = r.ToPort
+		} else {
+			to += " port " + r.ToPort
+		}
+	}
+	if r.Protocol != "" {
+		to += "/" + r.Protocol
+	}
+	parts = append(parts, to)
+
+	from := r.From
+	if r.FromPort != "" {
+		from += " port " + r.FromPort
+	}
+	parts = append(parts, "from", from)
+
+	if r.Interface != "" {
+		parts = append(parts, "on", r.Interface)
+	}
+	if r.InterfaceOut != "" {
+		parts = append(parts, "out on", r.InterfaceOut)
+	}
+	if r.V6 {
+		parts = append(parts, "(v6)")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// AnalyzeRules flags rules that can never match or have no effect because an
+// earlier rule already covers all of their traffic. Rules are expected in
+// evaluation order and each rule gets at most one finding.
+func AnalyzeRules(rules []domain.Rule) []domain.Finding {
+	var findings []domain.Finding
+
+	for i, rule := range rules {
+		for _, earlier := range rules[:i] {
+			if !RuleCovers(earlier, rule) {
+				continue
+			}
+
+			identical := RuleCovers(rule, earlier)
+			sameAction := earlier.Action == rule.Action
+
+			f := domain.Finding{Rule: rule.Number, Other: earlier.Number}
+			switch {
+			case identical && sameAction:
+				f.Kind = FindingDuplicate
+				f.Explanation = fmt.Sprintf("[%d] duplicates [%d]: %s", rule.Number, earlier.Number, DescribeRule(rule))
+			case identical:
+				f.Kind = FindingContradictory
+				f.Explanation = fmt.Sprintf("[%d] contradicts [%d]: %q and %q match the same traffic, only [%d] takes effect",
+					rule.Number, earlier.Number, DescribeRule(rule), DescribeRule(earlier), earlier.Number)
+			case sameAction:
+				f.Kind = FindingRedundant
+				f.Explanation = fmt.Sprintf("[%d] is redundant: %q is already covered by [%d] %q",
+					rule.Number, DescribeRule(rule), earlier.Number, DescribeRule(earlier))
+			default:
+				f.Kind = FindingShadowed
+				f.Explanation = fmt.Sprintf("[%d] can never match: %q is shadowed by [%d] %q",
+					rule.Number, DescribeRule(rule), earlier.Number, DescribeRule(earlier))
+			}
+
+			findings = append(findings, f)
+			break
+		}
+	}
+
+	return findings
+}
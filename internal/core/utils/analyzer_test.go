@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestAnalyzeRules(t *testing.T) {
+	rows := []string{
+		"[ 1] 22/tcp ALLOW IN Anywhere",
+		"[ 2] 22/tcp DENY IN 10.0.0.0/8",
+		"[ 3] 80 ALLOW IN Anywhere",
+		"[ 4] 80 ALLOW IN Anywhere",
+		"[ 5] 443 ALLOW IN 192.168.0.0/16",
+		"[ 6] 443 DENY IN 192.168.0.0/16",
+		"[ 7] Anywhere ALLOW IN 1.2.3.4",
+		"[ 8] 25 ALLOW IN 1.2.3.4",
+		"[ 9] 22/tcp (v6) DENY IN Anywhere (v6)",
+		"[10] 53 ALLOW OUT Anywhere",
+	}
+
+	findings := AnalyzeRules(ParseRules(rows))
+
+	expected := []struct {
+		kind        string
+		rule, other int
+	}{
+		{FindingShadowed, 2, 1},
+		{FindingDuplicate, 4, 3},
+		{FindingContradictory, 6, 5},
+		{FindingRedundant, 8, 7},
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	for i, e := range expected {
+		f := findings[i]
+		if f.Kind != e.kind || f.Rule != e.rule || f.Other != e.other {
+			t.Errorf("finding %d: got %s [%d] by [%d], want %s [%d] by [%d]", i, f.Kind, f.Rule, f.Other, e.kind, e.rule, e.other)
+		}
+	}
+
+	if findings[0].Explanation != `[2] can never match: "DENY IN 22/tcp from 10.0.0.0/8" is shadowed by [1] "ALLOW IN 22/tcp from any"` {
+		t.Errorf("unexpected explanation: %s", findings[0].Explanation)
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+// RuleKey identifies what a rule does, regardless of its number and comment,
+// so the same rule has the same key on every host.
+func RuleKey(rule domain.Rule) string {
+	direction := strings.ToUpper(rule.Direction)
+	if direction == "" {
+		direction = "IN"
+	}
+	v6 := ""
+	if rule.V6 {
+		v6 = "v6"
+	}
+	return strings.Join([]string{
+		strings.ToUpper(rule.Action), direction,
+		canonicalAddress(rule.To), canonicalPorts(rule.ToPort),
+		canonicalAddress(rule.From), canonicalPorts(rule.FromPort),
+		strings.ToLower(rule.Protocol), rule.Interface, rule.InterfaceOut, v6,
+	}, "|")
+}
+
+func canonicalAddress(address string) string {
+	if address == "" || isAny(address) {
+		return "any"
+	}
+	prefix, err := parsePrefix(address)
+	if err != nil {
+		return address
+	}
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
+
+func canonicalPorts(ports string) string {
+	items := strings.Split(ports, ",")
+	slices.Sort(items)
+	return strings.Join(items, ",")
+}
+
+// DiffRules pairs the rules of two rulesets doing the same thing, whatever
+// their numbers. Rules follow the order of left, those only in right coming
+// right before the next rule they precede which is in both.
+func DiffRules(left, right []domain.Rule) []domain.RuleDiff {
+	// Each rule of left is paired with the first rule of right with the same key left
+	pairs := make([]int, len(left))
+	paired := make([]bool, len(right))
+	for i, l := range left {
+		pairs[i] = -1
+		key := RuleKey(l)
+		for j, r := range right {
+			if !paired[j] && RuleKey(r) == key {
+				pairs[i], paired[j] = j, true
+				break
+			}
+		}
+	}
+
+	var diffs []domain.RuleDiff
+	next := 0
+	flushRight := func(until int) {
+		for ; next < until; next++ {
+			if !paired[next] {
+				diffs = append(diffs, domain.RuleDiff{Right: &right[next]})
+			}
+		}
+	}
+	for i := range left {
+		if j := pairs[i]; j != -1 {
+			flushRight(j)
+			diffs = append(diffs, domain.RuleDiff{Left: &left[i], Right: &right[j]})
+		} else {
+			diffs = append(diffs, domain.RuleDiff{Left: &left[i]})
+		}
+	}
+	flushRight(len(right))
+
+	return diffs
+}
+
+// ParseRulesFile reads the rules of an exported file, holding either the
+// output of `ufw status numbered` or ufw commands such as those of `ufw show
+// added`. Commands are numbered in the order they come.
+func ParseRulesFile(content string) []domain.Rule {
+	var rules []domain.Rule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if rule := ParseRule(line); rule != nil {
+			rules = append(rules, *rule)
+			continue
+		}
+		if !strings.HasPrefix(line, "ufw ") {
+			continue
+		}
+		fv, err := ParseUfwCommand(line)
+		if err != nil {
+			continue
+		}
+		for _, rule := range RuleFromForm(*fv) {
+			rule.Number = len(rules) + 1
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
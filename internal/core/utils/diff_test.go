@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestRuleKey(t *testing.T) {
+	a := domain.Rule{Number: 1, Action: "ALLOW", Direction: "IN", To: "any", ToPort: "80,443", From: "10.0.0.0/8", Protocol: "tcp", Comment: "web"}
+	b := domain.Rule{Number: 7, Action: "ALLOW", To: "Anywhere", ToPort: "443,80", From: "10.1.2.3/8", Protocol: "tcp"}
+	if RuleKey(a) != RuleKey(b) {
+		t.Errorf("%q and %q should be the same rule", RuleKey(a), RuleKey(b))
+	}
+
+	for _, other := range []domain.Rule{
+		{Action: "DENY", To: "any", ToPort: "80,443", From: "10.0.0.0/8", Protocol: "tcp"},
+		{Action: "ALLOW", To: "any", ToPort: "80,443", From: "10.0.0.0/8", Protocol: "udp"},
+		{Action: "ALLOW", To: "any", ToPort: "80,443", From: "10.0.0.0/8", Protocol: "tcp", V6: true},
+		{Action: "ALLOW", To: "any", ToPort: "80,443", From: "10.0.0.0/8", Protocol: "tcp", Interface: "eth0"},
+	} {
+		if RuleKey(a) == RuleKey(other) {
+			t.Errorf("%+v should differ from %+v", other, a)
+		}
+	}
+}
+
+func TestDiffRules(t *testing.T) {
+	left := ParseRules([]string{
+		"[ 1] 22/tcp ALLOW IN 10.0.0.0/8",
+		"[ 2] 80/tcp ALLOW IN Anywhere",
+		"[ 3] 3306 DENY IN Anywhere",
+		"[ 4] 443/tcp ALLOW IN Anywhere",
+	})
+	right := ParseRules([]string{
+		"[ 1] 22/tcp ALLOW IN 10.0.0.0/8",
+		"[ 2] 25/tcp ALLOW IN Anywhere",
+		"[ 3] 443/tcp ALLOW IN Anywhere",
+		"[ 4] 80/tcp ALLOW IN Anywhere",
+		"[ 5] 8080/tcp ALLOW IN Anywhere",
+	})
+
+	var got [][2]int
+	for _, d := range DiffRules(left, right) {
+		pair := [2]int{}
+		if d.Left != nil {
+			pair[0] = d.Left.Number
+		}
+		if d.Right != nil {
+			pair[1] = d.Right.Number
+		}
+		got = append(got, pair)
+	}
+
+	expected := [][2]int{{1, 1}, {0, 2}, {2, 4}, {3, 0}, {4, 3}, {0, 5}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestParseRulesFile(t *testing.T) {
+	status := `Status: active
+
+     To                         Action      From
+     --                         ------      ----
+[ 1] 22/tcp                     ALLOW IN    Anywhere
+[ 2] 22/tcp (v6)                ALLOW IN    Anywhere (v6)
+`
+	added := `Added user rules (see 'ufw status' for running firewall):
+ufw allow 22/tcp
+`
+
+	fromStatus, fromAdded := ParseRulesFile(status), ParseRulesFile(added)
+	if len(fromStatus) != 2 || len(fromAdded) != 2 {
+		t.Fatalf("expected a v4 and a v6 rule, got %+v and %+v", fromStatus, fromAdded)
+	}
+	for i := range fromStatus {
+		if RuleKey(fromStatus[i]) != RuleKey(fromAdded[i]) {
+			t.Errorf("%q and %q should be the same rule", RuleKey(fromStatus[i]), RuleKey(fromAdded[i]))
+		}
+	}
+	if fromAdded[1].Number != 2 {
+		t.Errorf("commands should be numbered in order, got %d", fromAdded[1].Number)
+	}
+}
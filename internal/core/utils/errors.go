@@ -0,0 +1,36 @@
+package utils
+
+import "strings"
+
+// ufwErrors maps the errors of ufw to the form field they most likely relate
+// to, the more specific messages first.
+var ufwErrors = []struct {
+	message string
+	field   string
+}{
+	{"bad source address", "From"},
+	{"bad destination address", "To"},
+	{"invalid 'from' address", "From"},
+	{"invalid 'to' address", "To"},
+	{"ip version", "To"},
+	{"with multiple ports", "Protocol"},
+	{"port with protocol", "Protocol"},
+	{"protocol", "Protocol"},
+	{"port", "Port"},
+	{"could not find a profile", "Port"},
+	{"interface", "Interface"},
+	{"comment", "Comment"},
+	{"address", "To"},
+}
+
+// ErrorField returns the form field an error of ufw most likely relates to,
+// or "" when it cannot tell.
+func ErrorField(trace string) string {
+	lower := strings.ToLower(trace)
+	for _, e := range ufwErrors {
+		if strings.Contains(lower, e.message) {
+			return e.field
+		}
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestErrorField(t *testing.T) {
+	tests := []struct {
+		trace    string
+		expected string
+	}{
+		{"ERROR: Bad source address", "From"},
+		{"ERROR: Bad destination address", "To"},
+		{"ERROR: Bad port '70000'", "Port"},
+		{"ERROR: Must specify 'tcp' or 'udp' with multiple ports", "Protocol"},
+		{"ERROR: Invalid port with protocol 'ah'", "Protocol"},
+		{"ERROR: Unsupported protocol 'icmp'", "Protocol"},
+		{"ERROR: Could not find a profile matching 'Web'", "Port"},
+		{"ERROR: Invalid interface clause", "Interface"},
+		{"ERROR: Invalid position '12'", ""},
+		{"ERROR: Wrong number of arguments", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorField(tt.trace); got != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.trace, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+var groupNameRegexp = regexp.MustCompile(`^@[A-Za-z0-9_-]+$`)
+
+// IsGroup reports whether an address field refers to an address group, e.g. @office.
+func IsGroup(input string) bool {
+	return groupNameRegexp.MatchString(input)
+}
+
+// ParseGroups parses address groups written one per line as
+// `@office = 203.0.113.0/24, 198.51.100.7`. Lines starting with # are ignored.
+func ParseGroups(content string) (map[string][]string, error) {
+	groups := map[string][]string{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, list, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || !IsGroup(name) {
+			return nil, fmt.Errorf("line %d: expected @name = addresses", i+1)
+		}
+
+		members, err := ParseMembers(list)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		groups[name] = members
+	}
+	return groups, nil
+}
+
+// ParseMembers parses a comma separated list of IP addresses and subnets.
+func ParseMembers(list string) ([]string, error) {
+	var members []string
+	for _, member := range strings.Split(list, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		if member == "any" || member == "Anywhere" || !IsAddress(member) {
+			return nil, fmt.Errorf("invalid address %q", member)
+		}
+		members = append(members, member)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("a group needs at least one address")
+	}
+	return members, nil
+}
+
+// FormatGroups writes groups back in the format read by ParseGroups.
+func FormatGroups(groups map[string][]string) string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s = %s\n", name, strings.Join(groups[name], ", "))
+	}
+	return b.String()
+}
+
+// CommentGroups returns the groups a rule was derived from, which are
+// recorded at the end of its comment.
+func CommentGroups(comment string) []string {
+	var names []string
+	for _, word := range strings.Fields(comment) {
+		if IsGroup(word) {
+			names = append(names, word)
+		}
+	}
+	return names
+}
+
+// tagComment records the groups a rule is derived from in its comment.
+func tagComment(comment string, names ...string) string {
+	for _, name := range names {
+		if !strings.Contains(" "+comment+" ", " "+name+" ") {
+			comment = strings.TrimSpace(comment + " " + name)
+		}
+	}
+	return comment
+}
+
+func groupMembers(address string, groups map[string][]string) ([]string, string, error) {
+	if !IsGroup(address) {
+		return []string{address}, "", nil
+	}
+	members, ok := groups[address]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown address group %s", address)
+	}
+	return members, address, nil
+}
+
+// ExpandGroups turns a rule using address groups in To or From into one rule
+// per member, skipping the combinations mixing IPv4 and IPv6 addresses.
+func ExpandGroups(fv domain.FormValues, groups map[string][]string) ([]domain.FormValues, error) {
+	tos, toGroup, err := groupMembers(fv.To, groups)
+	if err != nil {
+		return nil, err
+	}
+	froms, fromGroup, err := groupMembers(fv.From, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	var expanded []domain.FormValues
+	for _, to := range tos {
+		for _, from := range froms {
+			if isV6(to) != isV6(from) && IsAddress(to) && IsAddress(from) && to != "any" && from != "any" {
+				continue
+			}
+			rule := fv
+			rule.To, rule.From = to, from
+			rule.Comment = tagComment(fv.Comment, toGroup, fromGroup)
+			expanded = append(expanded, rule)
+		}
+	}
+	return expanded, nil
+}
+
+// FormFromRule returns the form values describing a parsed rule.
+func FormFromRule(rule domain.Rule) domain.FormValues {
+	fv := domain.FormValues{
+		To:        rule.To,
+		Port:      rule.ToPort,
+		Interface: rule.Interface,
+		Protocol:  rule.Protocol,
+		Action:    rule.Action + " " + rule.Direction,
+		From:      rule.From,
+		Comment:   rule.Comment,
+	}
+	switch rule.Direction {
+	case "OUT":
+		fv.Interface, fv.InterfaceOut = "", rule.Interface
+	case "FWD":
+		fv.InterfaceOut = rule.InterfaceOut
+	}
+	if fv.To == "any" {
+		fv.To = ""
+	}
+	if fv.From == "any" {
+		fv.From = ""
+	}
+	return fv
+}
+
+// GroupTemplates rebuilds the rules using the group name from the rules
+// derived from it, given the members they were expanded from. It returns
+// the templates and the numbers of the derived rules.
+func GroupTemplates(rules []domain.Rule, name string, members []string) ([]domain.FormValues, []int) {
+	isMember := map[string]bool{}
+	for _, member := range members {
+		isMember[member] = true
+	}
+
+	var templates []domain.FormValues
+	var numbers []int
+	seen := map[domain.FormValues]bool{}
+	for _, rule := range rules {
+		tagged := false
+		for _, group := range CommentGroups(rule.Comment) {
+			tagged = tagged || group == name
+		}
+		if !tagged {
+			continue
+		}
+		numbers = append(numbers, rule.Number)
+
+		fv := FormFromRule(rule)
+		if isMember[fv.To] {
+			fv.To = name
+		}
+		if isMember[fv.From] {
+			fv.From = name
+		}
+		if !seen[fv] {
+			seen[fv] = true
+			templates = append(templates, fv)
+		}
+	}
+	return templates, numbers
+}
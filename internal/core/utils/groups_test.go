@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestParseGroups(t *testing.T) {
+	content := "# Shared subnets\n@office = 203.0.113.0/24, 198.51.100.7\n\n@vpn=10.8.0.0/24,2001:db8::/64\n"
+	expected := map[string][]string{
+		"@office": {"203.0.113.0/24", "198.51.100.7"},
+		"@vpn":    {"10.8.0.0/24", "2001:db8::/64"},
+	}
+
+	groups, err := ParseGroups(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("got %v, want %v", groups, expected)
+	}
+	if got, _ := ParseGroups(FormatGroups(groups)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected formatted groups to parse back, got %v", got)
+	}
+
+	for _, invalid := range []string{"office = 10.0.0.1", "@office 10.0.0.1", "@office = 10.0.0.300", "@office = any", "@office ="} {
+		if _, err := ParseGroups(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestExpandGroups(t *testing.T) {
+	groups := map[string][]string{
+		"@office": {"203.0.113.0/24", "198.51.100.7"},
+		"@vpn":    {"10.8.0.0/24", "2001:db8::/64"},
+	}
+
+	expanded, err := ExpandGroups(domain.FormValues{Port: "22", Action: "ALLOW IN", From: "@office", Comment: "SSH"}, groups)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []domain.FormValues{
+		{Port: "22", Action: "ALLOW IN", From: "203.0.113.0/24", Comment: "SSH @office"},
+		{Port: "22", Action: "ALLOW IN", From: "198.51.100.7", Comment: "SSH @office"},
+	}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("got %+v, want %+v", expanded, expected)
+	}
+
+	// IPv6 members cannot be combined with IPv4 addresses
+	expanded, _ = ExpandGroups(domain.FormValues{To: "192.168.0.1", Action: "ALLOW IN", From: "@vpn"}, groups)
+	expected = []domain.FormValues{{To: "192.168.0.1", Action: "ALLOW IN", From: "10.8.0.0/24", Comment: "@vpn"}}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("got %+v, want %+v", expanded, expected)
+	}
+
+	if _, err := ExpandGroups(domain.FormValues{From: "@unknown"}, groups); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+}
+
+func TestGroupTemplates(t *testing.T) {
+	rules := ParseRules([]string{
+		"[ 1] 80/tcp ALLOW IN Anywhere",
+		"[ 2] 22 ALLOW IN 203.0.113.0/24 # SSH @office",
+		"[ 3] 22 ALLOW IN 198.51.100.7 # SSH @office",
+		"[ 4] 10.0.0.1 DENY OUT 198.51.100.7 on eth0 (out) # @office",
+		"[ 5] 22 ALLOW IN 10.0.0.0/8 # SSH",
+	})
+
+	templates, numbers := GroupTemplates(rules, "@office", []string{"203.0.113.0/24", "198.51.100.7"})
+
+	expected := []domain.FormValues{
+		{Port: "22", Action: "ALLOW IN", From: "@office", Comment: "SSH @office"},
+		{To: "10.0.0.1", InterfaceOut: "eth0", Action: "DENY OUT", From: "@office", Comment: "@office"},
+	}
+	if !reflect.DeepEqual(templates, expected) {
+		t.Errorf("got %+v, want %+v", templates, expected)
+	}
+	if !reflect.DeepEqual(numbers, []int{2, 3, 4}) {
+		t.Errorf("expected the derived rules 2, 3 and 4, got %v", numbers)
+	}
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+const (
+	ExposureAnywhere = "anywhere"
+	ExposureSome     = "some sources"
+	ExposureNone     = "not at all"
+	ExposureLocal    = "local only"
+)
+
+var processRegexp = regexp.MustCompile(`users:\(\("([^"]+)"`)
+
+// ParseSS parses the output of `ss -tulpnH` into the listening sockets.
+func ParseSS(output string) []domain.Listener {
+	var listeners []domain.Listener
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || (fields[0] != "tcp" && fields[0] != "udp") {
+			continue
+		}
+
+		idx := strings.LastIndex(fields[4], ":")
+		if idx == -1 {
+			continue
+		}
+		host, port := fields[4][:idx], fields[4][idx+1:]
+		host = strings.Trim(host, "[]")
+
+		l := domain.Listener{Protocol: fields[0], Port: port}
+		if i := strings.Index(host, "%"); i != -1 {
+			host, l.Interface = host[:i], host[i+1:]
+		}
+		l.Address, l.V6 = normalizeListenAddress(host)
+
+		if match := processRegexp.FindStringSubmatch(line); match != nil {
+			l.Process = match[1]
+		}
+		listeners = appendListener(listeners, l)
+	}
+	return listeners
+}
+
+// ParseProcNet parses /proc/net/{tcp,udp}{,6} into the listening sockets.
+// Process names are not available there.
+func ParseProcNet(content string, protocol string) []domain.Listener {
+	// TCP sockets must be listening, UDP ones are merely bound
+	state := "0A"
+	if protocol == "udp" {
+		state = "07"
+	}
+
+	var listeners []domain.Listener
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[3] != state {
+			continue
+		}
+
+		hexAddress, hexPort, found := strings.Cut(fields[1], ":")
+		if !found {
+			continue
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			continue
+		}
+		raw, err := hex.DecodeString(hexAddress)
+		if err != nil || (len(raw) != 4 && len(raw) != 16) {
+			continue
+		}
+
+		// The kernel prints each 32-bit word in host (little-endian) order
+		for i := 0; i < len(raw); i += 4 {
+			raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+		}
+		addr, _ := netip.AddrFromSlice(raw)
+
+		l := domain.Listener{Protocol: protocol, Port: strconv.FormatUint(port, 10)}
+		l.Address, l.V6 = normalizeListenAddress(addr.String())
+		listeners = appendListener(listeners, l)
+	}
+	return listeners
+}
+
+// normalizeListenAddress maps wildcard addresses to any, keeping their family.
+func normalizeListenAddress(host string) (string, bool) {
+	switch host {
+	case "*", "0.0.0.0":
+		return "any", false
+	case "::":
+		return "any", true
+	}
+	// IPv4-mapped sockets receive IPv4 traffic
+	if addr, err := netip.ParseAddr(host); err == nil && addr.Is4In6() {
+		host = addr.Unmap().String()
+	}
+	return host, isV6(host)
+}
+
+// appendListener skips sockets already listed, e.g. shared through SO_REUSEPORT.
+func appendListener(listeners []domain.Listener, l domain.Listener) []domain.Listener {
+	for _, other := range listeners {
+		if other.Protocol == l.Protocol && other.Address == l.Address && other.Port == l.Port && other.V6 == l.V6 && other.Interface == l.Interface {
+			return listeners
+		}
+	}
+	return append(listeners, l)
+}
+
+func isLoopback(address string) bool {
+	addr, err := netip.ParseAddr(address)
+	return err == nil && addr.IsLoopback()
+}
+
+// Exposure tells from where incoming traffic can reach the listener given
+// the rules, in evaluation order, and the default policies.
+func Exposure(rules []domain.Rule, defaults map[string]string, l domain.Listener) string {
+	if isLoopback(l.Address) || l.Interface == "lo" {
+		return ExposureLocal
+	}
+
+	partialAllow, partialDeny := false, false
+	for _, rule := range rules {
+		if rule.Direction != "IN" || rule.V6 != l.V6 ||
+			!fieldContains(rule.Protocol, l.Protocol) || !PortContains(rule.ToPort, l.Port) {
+			continue
+		}
+		if l.Address != "any" && !AddressContains(rule.To, l.Address) {
+			continue
+		}
+
+		allowed := IsAllowed(rule.Action)
+
+		// Rules restricted to some sources, interfaces or local addresses only
+		// decide for part of the traffic
+		if rule.From != "any" || rule.FromPort != "" || rule.Interface != "" || rule.To != "any" && l.Address == "any" {
+			partialAllow = partialAllow || allowed
+			partialDeny = partialDeny || !allowed
+			continue
+		}
+
+		return exposureOf(allowed, partialAllow, partialDeny)
+	}
+
+	return exposureOf(strings.EqualFold(defaults["incoming"], "allow"), partialAllow, partialDeny)
+}
+
+func exposureOf(allowed bool, partialAllow bool, partialDeny bool) string {
+	switch {
+	case allowed && partialDeny, !allowed && partialAllow:
+		return ExposureSome
+	case allowed:
+		return ExposureAnywhere
+	}
+	return ExposureNone
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestParseSS(t *testing.T) {
+	output := `udp   UNCONN 0      0          127.0.0.53%lo:53         0.0.0.0:*    users:(("systemd-resolve",pid=612,fd=13))
+tcp   LISTEN 0      4096             0.0.0.0:22         0.0.0.0:*    users:(("sshd",pid=900,fd=3))
+tcp   LISTEN 0      4096                [::]:22            [::]:*    users:(("sshd",pid=900,fd=4))
+tcp   LISTEN 0      511                    *:80               *:*    users:(("apache2",pid=1001,fd=4),("apache2",pid=1002,fd=4))
+tcp   LISTEN 0      511                    *:80               *:*    users:(("apache2",pid=1003,fd=4))
+tcp   LISTEN 0      244       192.168.1.10:5432         0.0.0.0:*
+udp   UNCONN 0      0     [fe80::1%eth0]:546              [::]:*    users:(("dhclient",pid=700,fd=6))
+`
+
+	expected := []domain.Listener{
+		{Protocol: "udp", Address: "127.0.0.53", Port: "53", Interface: "lo", Process: "systemd-resolve"},
+		{Protocol: "tcp", Address: "any", Port: "22", Process: "sshd"},
+		{Protocol: "tcp", Address: "any", Port: "22", Process: "sshd", V6: true},
+		{Protocol: "tcp", Address: "any", Port: "80", Process: "apache2"},
+		{Protocol: "tcp", Address: "192.168.1.10", Port: "5432"},
+		{Protocol: "udp", Address: "fe80::1", Port: "546", Interface: "eth0", Process: "dhclient", V6: true},
+	}
+
+	if got := ParseSS(output); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v\nwant %+v", got, expected)
+	}
+}
+
+func TestParseProcNet(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 20000 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0277 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 20001 1 0000000000000000 100 0 0 10 0
+   2: 0A01A8C0:0016 6401A8C0:D431 01 00000000:00000000 00:00000000 00000000     0        0 20002 1 0000000000000000 100 0 0 10 0
+`
+	expected := []domain.Listener{
+		{Protocol: "tcp", Address: "any", Port: "22"},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: "631"},
+	}
+	if got := ParseProcNet(tcp, "tcp"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v\nwant %+v", got, expected)
+	}
+
+	udp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  10: 00000000000000000000000000000000:14E9 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000   104        0 18000 2 0000000000000000 0
+  11: 0000000000000000FFFF00000100007F:0035 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000   104        0 18001 2 0000000000000000 0
+`
+	expected = []domain.Listener{
+		{Protocol: "udp", Address: "any", Port: "5353", V6: true},
+		{Protocol: "udp", Address: "127.0.0.1", Port: "53"},
+	}
+	if got := ParseProcNet(udp6, "udp"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v\nwant %+v", got, expected)
+	}
+}
+
+func TestExposure(t *testing.T) {
+	defaults := map[string]string{"incoming": "deny", "outgoing": "allow"}
+
+	tests := []struct {
+		name     string
+		rows     []string
+		listener domain.Listener
+		expected string
+	}{
+		{
+			name:     "allowed from anywhere",
+			rows:     []string{"[ 1] 22/tcp ALLOW IN Anywhere"},
+			listener: domain.Listener{Protocol: "tcp", Address: "any", Port: "22"},
+			expected: ExposureAnywhere,
+		},
+		{
+			name:     "allowed from some sources",
+			rows:     []string{"[ 1] 5432/tcp ALLOW IN 10.0.0.0/8"},
+			listener: domain.Listener{Protocol: "tcp", Address: "any", Port: "5432"},
+			expected: ExposureSome,
+		},
+		{
+			name:     "denied before being allowed",
+			rows:     []string{"[ 1] 22/tcp DENY IN Anywhere", "[ 2] 22/tcp ALLOW IN 10.0.0.0/8"},
+			listener: domain.Listener{Protocol: "tcp", Address: "any", Port: "22"},
+			expected: ExposureNone,
+		},
+		{
+			name:     "partly denied then allowed",
+			rows:     []string{"[ 1] 80/tcp DENY IN 203.0.113.0/24", "[ 2] 80,443/tcp ALLOW IN Anywhere"},
+			listener: domain.Listener{Protocol: "tcp", Address: "any", Port: "80"},
+			expected: ExposureSome,
+		},
+		{
+			name:     "allowed on a single interface",
+			rows:     []string{"[ 1] Anywhere on wg0 ALLOW IN Anywhere"},
+			listener: domain.Listener{Protocol: "udp", Address: "any", Port: "53"},
+			expected: ExposureSome,
+		},
+		{
+			name:     "other family",
+			rows:     []string{"[ 1] 22/tcp ALLOW IN Anywhere"},
+			listener: domain.Listener{Protocol: "tcp", Address: "any", Port: "22", V6: true},
+			expected: ExposureNone,
+		},
+		{
+			name:     "other protocol",
+			rows:     []string{"[ 1] 53/tcp ALLOW IN Anywhere"},
+			listener: domain.Listener{Protocol: "udp", Address: "any", Port: "53"},
+			expected: ExposureNone,
+		},
+		{
+			name:     "loopback",
+			rows:     []string{"[ 1] 631/tcp ALLOW IN Anywhere"},
+			listener: domain.Listener{Protocol: "tcp", Address: "127.0.0.1", Port: "631"},
+			expected: ExposureLocal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Exposure(ParseRules(tt.rows), defaults, tt.listener); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if got := Exposure(nil, map[string]string{"incoming": "allow"}, domain.Listener{Protocol: "tcp", Address: "any", Port: "8080"}); got != ExposureAnywhere {
+		t.Errorf("expected an allow policy to expose the listener, got %q", got)
+	}
+}
@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+// Query reports whether a rule, along with its formatted text, matches a search.
+type Query func(rule domain.Rule, text string) bool
+
+// QueryFields lists the fields a query can filter on.
+var QueryFields = []string{"port", "to", "from", "action", "dir", "iface", "proto", "comment"}
+
+var queryAliases = map[string]string{"direction": "dir", "interface": "iface", "protocol": "proto"}
+
+// ParseQuery compiles a search such as
+// `port:22 action:deny from:10.0.0.0/8 iface:eth0 comment:~backup`.
+// Terms are ANDed unless separated by OR, can be negated with NOT (or a
+// leading !) and grouped with parentheses. A value starting with ~ is a
+// regular expression. A bare address matches the rules affecting it and any
+// other bare word is matched against the whole rule.
+func ParseQuery(input string) (Query, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return func(domain.Rule, string) bool { return true }, nil
+	}
+
+	p := &queryParser{tokens: tokens}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return q, nil
+}
+
+func tokenizeQuery(input string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+			current.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	flush()
+
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) parseOr() (Query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(rule domain.Rule, text string) bool { return l(rule, text) || right(rule, text) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Query, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		next := p.peek()
+		if next == "" || next == ")" || strings.EqualFold(next, "OR") {
+			return left, nil
+		}
+		if strings.EqualFold(next, "AND") {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(rule domain.Rule, text string) bool { return l(rule, text) && right(rule, text) }
+	}
+}
+
+func (p *queryParser) parseUnary() (Query, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of query")
+	case strings.EqualFold(token, "NOT"):
+		p.pos++
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(rule domain.Rule, text string) bool { return !q(rule, text) }, nil
+	case token == "(":
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return q, nil
+	case token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR"):
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+
+	p.pos++
+	if len(token) > 1 && token[0] == '!' {
+		q, err := parseTerm(token[1:])
+		if err != nil {
+			return nil, err
+		}
+		return func(rule domain.Rule, text string) bool { return !q(rule, text) }, nil
+	}
+	return parseTerm(token)
+}
+
+func parseTerm(term string) (Query, error) {
+	field, value, found := strings.Cut(term, ":")
+	field = strings.ToLower(field)
+	if alias, ok := queryAliases[field]; ok {
+		field = alias
+	}
+
+	known := false
+	for _, f := range QueryFields {
+		known = known || f == field
+	}
+
+	// Bare addresses match the rules affecting them, other words are matched
+	// against the whole rule
+	if term != "any" && term != "Anywhere" && IsAddress(term) {
+		return func(rule domain.Rule, text string) bool { return RuleAffects(rule, term, "", "") }, nil
+	}
+	if !found || !known {
+		re, err := regexp.Compile("(?i)" + term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", term, err)
+		}
+		return func(rule domain.Rule, text string) bool { return re.MatchString(text) }, nil
+	}
+	if value == "" {
+		return nil, fmt.Errorf("missing value for %s", field)
+	}
+
+	if strings.HasPrefix(value, "~") {
+		re, err := regexp.Compile("(?i)" + value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for %s: %w", field, err)
+		}
+		return func(rule domain.Rule, text string) bool { return re.MatchString(ruleField(rule, field)) }, nil
+	}
+
+	return func(rule domain.Rule, text string) bool { return matchField(rule, field, value) }, nil
+}
+
+// ruleField returns the text of a rule field as used by regular expressions.
+func ruleField(rule domain.Rule, field string) string {
+	switch field {
+	case "port":
+		return rule.ToPort
+	case "to":
+		return rule.To
+	case "from":
+		return rule.From
+	case "action":
+		return rule.Action + "-" + rule.Direction
+	case "dir":
+		return rule.Direction
+	case "iface":
+		return strings.TrimSpace(rule.Interface + " " + rule.InterfaceOut)
+	case "proto":
+		return rule.Protocol
+	case "comment":
+		return rule.Comment
+	}
+	return ""
+}
+
+func matchField(rule domain.Rule, field string, value string) bool {
+	switch field {
+	case "port":
+		if strings.EqualFold(value, "any") {
+			return rule.ToPort == ""
+		}
+		if rule.ToPort != "" && IsPortSpec(rule.ToPort) && IsPortSpec(value) {
+			return PortContains(rule.ToPort, value)
+		}
+		return strings.EqualFold(rule.ToPort, value)
+	case "to", "from":
+		address := rule.To
+		if field == "from" {
+			address = rule.From
+		}
+		if value == "any" || value == "Anywhere" {
+			return address == "any"
+		}
+		if IsAddress(value) {
+			return address != "any" && AddressContains(value, address)
+		}
+		return strings.Contains(strings.ToLower(address), strings.ToLower(value))
+	case "action":
+		value = strings.ToUpper(strings.ReplaceAll(value, " ", "-"))
+		return rule.Action == value || rule.Action+"-"+rule.Direction == value
+	case "dir":
+		return strings.EqualFold(rule.Direction, value)
+	case "iface":
+		return strings.EqualFold(rule.Interface, value) || strings.EqualFold(rule.InterfaceOut, value)
+	case "proto":
+		if strings.EqualFold(value, "any") {
+			return rule.Protocol == ""
+		}
+		return strings.EqualFold(rule.Protocol, value)
+	case "comment":
+		return strings.Contains(strings.ToLower(rule.Comment), strings.ToLower(value))
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	rows := []string{
+		"[ 1] 22/tcp ALLOW IN Anywhere",
+		"[ 2] 22/tcp DENY IN 10.0.0.0/8",
+		"[ 3] Anywhere on eth0 ALLOW IN 10.1.0.0/16 # backup server",
+		"[ 4] 80,443/tcp DENY IN Anywhere # Web",
+		"[ 5] 1.1.1.1 53 ALLOW OUT Anywhere on eth0 (out) # DNS backups",
+		"[ 6] 6000:6007/tcp LIMIT IN 192.168.1.10",
+	}
+
+	tests := []struct {
+		query    string
+		expected []int
+	}{
+		{"", []int{1, 2, 3, 4, 5, 6}},
+		{"port:22", []int{1, 2}},
+		{"port:443", []int{4}},
+		{"port:6003", []int{6}},
+		{"port:any", []int{3}},
+		{"action:deny", []int{2, 4}},
+		{"action:allow-out", []int{5}},
+		{"from:10.0.0.0/8", []int{2, 3}},
+		{"from:any", []int{1, 4, 5}},
+		{"iface:eth0", []int{3, 5}},
+		{"comment:~^backup", []int{3}},
+		{"comment:backup", []int{3, 5}},
+		{"port:22 action:deny", []int{2}},
+		{"port:22 AND action:deny", []int{2}},
+		{"action:limit OR comment:web", []int{4, 6}},
+		{"NOT action:allow", []int{2, 4, 6}},
+		{"!dir:in", []int{5}},
+		{"(port:22 OR port:80) action:deny", []int{2, 4}},
+		{"iface:eth0 NOT (dir:out OR from:10.0.0.0/8)", []int{}},
+		{`comment:"dns backups"`, []int{5}},
+		{"192.168", []int{6}},
+		{"10.0.0.5", []int{1, 2, 4}},
+		{"10.0.0.5 port:22", []int{1, 2}},
+		{"192.168.1.10 NOT from:any", []int{6}},
+	}
+
+	rules := ParseRules(rows)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			matches := []int{}
+			for i, rule := range rules {
+				if q(rule, FormatUfwRule(rows[i])) {
+					matches = append(matches, rule.Number)
+				}
+			}
+			if !reflect.DeepEqual(matches, tt.expected) {
+				t.Errorf("got %v, want %v", matches, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	for _, query := range []string{"(port:22", "port:22 OR", "port:", "comment:~(", `comment:"open`, "AND port:22", "port:22)"} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("expected an error for %q", query)
+		}
+	}
+}
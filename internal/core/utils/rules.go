@@ -0,0 +1,404 @@
+package utils
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+var reRuleIndex = regexp.MustCompile(`^\[\s*(\d+)\]`)
+
+// ParseRule parses a row of `ufw status numbered` into its fields.
+// It returns nil when the row is not a rule.
+func ParseRule(row string) *domain.Rule {
+	row = strings.TrimSpace(row)
+	m := reRuleIndex.FindStringSubmatch(row)
+	if m == nil {
+		return nil
+	}
+
+	rule := domain.Rule{}
+	rule.Number, _ = strconv.Atoi(m[1])
+	row = strings.TrimSpace(row[len(m[0]):])
+
+	if idx := strings.Index(row, "#"); idx != -1 {
+		rule.Comment = strings.TrimSpace(row[idx+1:])
+		row = row[:idx]
+	}
+	if strings.Contains(row, "(v6)") {
+		rule.V6 = true
+		row = strings.ReplaceAll(row, "(v6)", "")
+	}
+	row = strings.ReplaceAll(row, "(out)", "")
+
+	tokens := strings.Fields(row)
+	actionIdx := -1
+	for i, tok := range tokens {
+		if tok == "ALLOW" || tok == "DENY" || tok == "REJECT" || tok == "LIMIT" {
+			actionIdx = i
+			break
+		}
+	}
+	if actionIdx == -1 {
+		return nil
+	}
+
+	rule.Action = tokens[actionIdx]
+	rule.Direction = "IN"
+	fromIdx := actionIdx + 1
+	if fromIdx < len(tokens) {
+		if dir := tokens[fromIdx]; dir == "IN" || dir == "OUT" || dir == "FWD" {
+			rule.Direction = dir
+			fromIdx++
+		}
+	}
+
+	to, toPort, toProto, toIface := parseSide(tokens[:actionIdx])
+	from, fromPort, fromProto, fromIface := parseSide(tokens[fromIdx:])
+
+	rule.To, rule.ToPort = to, toPort
+	rule.From, rule.FromPort = from, fromPort
+	rule.Protocol = toProto
+	if rule.Protocol == "" {
+		rule.Protocol = fromProto
+	}
+
+	if rule.Direction == "FWD" {
+		rule.Interface = fromIface
+		rule.InterfaceOut = toIface
+	} else if toIface != "" {
+		rule.Interface = toIface
+	} else {
+		rule.Interface = fromIface
+	}
+
+	return &rule
+}
+
+// ParseRules parses every rule row of `ufw status numbered`, skipping the others.
+func ParseRules(rows []string) []domain.Rule {
+	var rules []domain.Rule
+	for _, row := range rows {
+		if rule := ParseRule(row); rule != nil {
+			rules = append(rules, *rule)
+		}
+	}
+	return rules
+}
+
+func parseSide(tokens []string) (address, port, proto, iface string) {
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok == "on" && i+1 < len(tokens):
+			iface = tokens[i+1]
+			i++
+		case tok == "-":
+		case tok == "tcp" || tok == "udp":
+			proto = tok
+		default:
+			value, p := splitProtocol(tok)
+			if p != "" {
+				proto = p
+			}
+			if address == "" && IsAddress(value) {
+				address = value
+			} else if port != "" && !IsPortSpec(value) {
+				// Application profiles may contain spaces, e.g. "Apache Full"
+				port += " " + value
+			} else if value != "" {
+				port = value
+			}
+		}
+	}
+
+	if address == "" || address == "Anywhere" {
+		address = "any"
+	}
+	return
+}
+
+func splitProtocol(input string) (string, string) {
+	for _, p := range []string{"tcp", "udp"} {
+		if strings.HasSuffix(input, "/"+p) {
+			return strings.TrimSuffix(input, "/"+p), p
+		}
+	}
+	return input, ""
+}
+
+// IsAddress reports whether input is an IP address, a CIDR or any/Anywhere.
+func IsAddress(input string) bool {
+	if input == "any" || input == "Anywhere" {
+		return true
+	}
+	_, err := parsePrefix(input)
+	return err == nil
+}
+
+func parsePrefix(input string) (netip.Prefix, error) {
+	if strings.Contains(input, "/") {
+		prefix, err := netip.ParsePrefix(input)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(input)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// AddressContains reports whether every address matched by inner is also matched by outer.
+func AddressContains(outer, inner string) bool {
+	if outer == "" || outer == "any" {
+		return true
+	}
+	if inner == "" || inner == "any" {
+		return false
+	}
+
+	o, err := parsePrefix(outer)
+	if err != nil {
+		return outer == inner
+	}
+	i, err := parsePrefix(inner)
+	if err != nil {
+		return false
+	}
+
+	return o.Bits() <= i.Bits() && o.Contains(i.Addr())
+}
+
+type portRange struct {
+	low, high int
+}
+
+func parsePorts(input string) ([]portRange, bool) {
+	var ranges []portRange
+	for _, part := range strings.Split(input, ",") {
+		bounds := strings.SplitN(part, ":", 2)
+		low, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, false
+		}
+		high := low
+		if len(bounds) == 2 {
+			if high, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, false
+			}
+		}
+		ranges = append(ranges, portRange{low, high})
+	}
+	return ranges, true
+}
+
+// IsPortSpec reports whether input is a port, a list of ports or a port range.
+func IsPortSpec(input string) bool {
+	_, ok := parsePorts(input)
+	return ok
+}
+
+// PortContains reports whether every port matched by inner is also matched by outer.
+// Ports can be single values, lists (80,443) or ranges (6000:6007); empty means any port.
+func PortContains(outer, inner string) bool {
+	if outer == "" {
+		return true
+	}
+	if inner == "" {
+		return false
+	}
+
+	o, ok := parsePorts(outer)
+	if !ok {
+		return outer == inner
+	}
+	i, ok := parsePorts(inner)
+	if !ok {
+		return false
+	}
+
+	for _, ir := range i {
+		covered := false
+		for _, or := range o {
+			if or.low <= ir.low && ir.high <= or.high {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldContains(outer, inner string) bool {
+	return outer == "" || outer == inner
+}
+
+// RuleCovers reports whether every packet matched by inner is also matched by outer.
+func RuleCovers(outer, inner domain.Rule) bool {
+	return outer.Direction == inner.Direction &&
+		outer.V6 == inner.V6 &&
+		fieldContains(outer.Protocol, inner.Protocol) &&
+		fieldContains(outer.Interface, inner.Interface) &&
+		fieldContains(outer.InterfaceOut, inner.InterfaceOut) &&
+		AddressContains(outer.To, inner.To) &&
+		AddressContains(outer.From, inner.From) &&
+		PortContains(outer.ToPort, inner.ToPort) &&
+		PortContains(outer.FromPort, inner.FromPort)
+}
+
+// AddressOverlaps reports whether a and b match at least one common address.
+func AddressOverlaps(a, b string) bool {
+	return AddressContains(a, b) || AddressContains(b, a)
+}
+
+// PortOverlaps reports whether a and b match at least one common port.
+func PortOverlaps(a, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+
+	x, okX := parsePorts(a)
+	y, okY := parsePorts(b)
+	if !okX || !okY {
+		return a == b
+	}
+	for _, rx := range x {
+		for _, ry := range y {
+			if rx.low <= ry.high && ry.low <= rx.high {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RuleAffects reports whether the rule can apply to traffic exchanged with
+// address on port with proto. Empty values are unconstrained.
+func RuleAffects(rule domain.Rule, address, port, proto string) bool {
+	if address != "" && address != "any" {
+		if rule.V6 != isV6(address) {
+			return false
+		}
+		// The address is compared to the remote peer, and to the local side
+		// only when the rule restricts it
+		peer, local := rule.From, rule.To
+		if rule.Direction == "OUT" {
+			peer, local = rule.To, rule.From
+		}
+		if !AddressOverlaps(peer, address) && (local == "any" || !AddressOverlaps(local, address)) {
+			return false
+		}
+	}
+	if proto != "" && rule.Protocol != "" && rule.Protocol != proto {
+		return false
+	}
+
+	return PortOverlaps(rule.ToPort, port)
+}
+
+// ParseUfwCommand parses a rule written in ufw syntax, such as the lines of
+// `ufw show added`, e.g. "ufw allow in on eth0 from 10.0.0.0/8 to any port 22 proto tcp".
+func ParseUfwCommand(command string) (*domain.FormValues, error) {
+	fv := domain.FormValues{}
+
+	if idx := strings.Index(command, " comment "); idx != -1 {
+		fv.Comment = strings.Trim(strings.TrimSpace(command[idx+len(" comment "):]), `'"`)
+		command = command[:idx]
+	}
+
+	tokens := strings.Fields(command)
+	if len(tokens) > 0 && tokens[0] == "ufw" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && tokens[0] == "--dry-run" {
+		tokens = tokens[1:]
+	}
+
+	route := false
+	if len(tokens) > 0 && tokens[0] == "route" {
+		route = true
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 1 && tokens[0] == "insert" {
+		tokens = tokens[2:]
+	} else if len(tokens) > 0 && tokens[0] == "prepend" {
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("missing action in %q", command)
+	}
+	action := strings.ToUpper(tokens[0])
+	if action != "ALLOW" && action != "DENY" && action != "REJECT" && action != "LIMIT" {
+		return nil, fmt.Errorf("unknown action %q in %q", tokens[0], command)
+	}
+
+	direction := "IN"
+	if route {
+		direction = "FWD"
+	}
+
+	side, lastDir := "", ""
+	for i := 1; i < len(tokens); i++ {
+		tok := tokens[i]
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		switch tok {
+		case "in", "out":
+			lastDir = tok
+			if !route && i == 1 {
+				direction = strings.ToUpper(tok)
+			}
+		case "on":
+			if lastDir == "out" {
+				fv.InterfaceOut = next
+			} else {
+				fv.Interface = next
+			}
+			i++
+		case "log", "log-all":
+		case "from", "to":
+			side = tok
+			if tok == "from" {
+				fv.From = next
+			} else {
+				fv.To = next
+			}
+			i++
+		case "port":
+			// Source ports are not supported by the forms
+			if side != "from" {
+				fv.Port = next
+			}
+			i++
+		case "proto":
+			fv.Protocol = next
+			i++
+		case "app":
+			fv.Port = next
+			i++
+		default:
+			fv.Port, fv.Protocol = splitProtocol(tok)
+		}
+	}
+
+	fv.Action = action + " " + direction
+	if fv.To == "any" {
+		fv.To = ""
+	}
+	if fv.From == "any" {
+		fv.From = ""
+	}
+
+	return &fv, nil
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected domain.Rule
+	}{
+		{
+			input:    "[ 1] 22/tcp                     LIMIT IN    Anywhere",
+			expected: domain.Rule{Number: 1, Action: "LIMIT", Direction: "IN", To: "any", ToPort: "22", From: "any", Protocol: "tcp"},
+		},
+		{
+			input:    "[ 2] 192.168.0.1 22/tcp ALLOW IN 192.168.1.50 # Admin host",
+			expected: domain.Rule{Number: 2, Action: "ALLOW", Direction: "IN", To: "192.168.0.1", ToPort: "22", From: "192.168.1.50", Protocol: "tcp", Comment: "Admin host"},
+		},
+		{
+			input:    "[ 3] 6000:6007/tcp              DENY IN     10.0.0.0/8",
+			expected: domain.Rule{Number: 3, Action: "DENY", Direction: "IN", To: "any", ToPort: "6000:6007", From: "10.0.0.0/8", Protocol: "tcp"},
+		},
+		{
+			input:    "[ 4] 22/tcp (v6)                ALLOW IN    Anywhere (v6)",
+			expected: domain.Rule{Number: 4, Action: "ALLOW", Direction: "IN", To: "any", ToPort: "22", From: "any", Protocol: "tcp", V6: true},
+		},
+		{
+			input:    "[ 5] 1.1.1.1 53                 ALLOW OUT   Anywhere on eth0 (out)",
+			expected: domain.Rule{Number: 5, Action: "ALLOW", Direction: "OUT", To: "1.1.1.1", ToPort: "53", From: "any", Interface: "eth0"},
+		},
+		{
+			input:    "[ 6] 3.3.3.3 on lo DENY FWD Anywhere on enp0s1",
+			expected: domain.Rule{Number: 6, Action: "DENY", Direction: "FWD", To: "3.3.3.3", From: "any", Interface: "enp0s1", InterfaceOut: "lo"},
+		},
+		{
+			input:    "[ 7] 10.0.0.0/24 - udp REJECT IN Anywhere",
+			expected: domain.Rule{Number: 7, Action: "REJECT", Direction: "IN", To: "10.0.0.0/24", From: "any", Protocol: "udp"},
+		},
+		{
+			input:    "[ 8] OpenSSH                    ALLOW IN    Anywhere",
+			expected: domain.Rule{Number: 8, Action: "ALLOW", Direction: "IN", To: "any", ToPort: "OpenSSH", From: "any"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rule := ParseRule(tt.input)
+			if rule == nil {
+				t.Fatalf("ParseRule returned nil for input %q", tt.input)
+			}
+			if !reflect.DeepEqual(*rule, tt.expected) {
+				t.Errorf("got %+v, want %+v", *rule, tt.expected)
+			}
+		})
+	}
+
+	if rule := ParseRule("Status: active"); rule != nil {
+		t.Errorf("expected nil for a non rule row, got %+v", *rule)
+	}
+}
+
+func TestAddressContains(t *testing.T) {
+	tests := []struct {
+		outer, inner string
+		expected     bool
+	}{
+		{"any", "10.0.0.5", true},
+		{"10.0.0.5", "any", false},
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.0/24", "10.0.0.0/16", false},
+		{"10.0.0.0/16", "10.0.1.0/24", true},
+		{"192.168.0.1", "192.168.0.1", true},
+		{"2001:db8::/32", "2001:db8::1", true},
+		{"10.0.0.0/8", "2001:db8::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := AddressContains(tt.outer, tt.inner); got != tt.expected {
+			t.Errorf("AddressContains(%q, %q): got %v, want %v", tt.outer, tt.inner, got, tt.expected)
+		}
+	}
+}
+
+func TestPortContains(t *testing.T) {
+	tests := []struct {
+		outer, inner string
+		expected     bool
+	}{
+		{"", "22", true},
+		{"22", "", false},
+		{"22", "22", true},
+		{"80,443", "443", true},
+		{"6000:6007", "6003", true},
+		{"6000:6007", "6005:6010", false},
+		{"1:1024", "22,80", true},
+		{"OpenSSH", "OpenSSH", true},
+		{"OpenSSH", "22", false},
+	}
+
+	for _, tt := range tests {
+		if got := PortContains(tt.outer, tt.inner); got != tt.expected {
+			t.Errorf("PortContains(%q, %q): got %v, want %v", tt.outer, tt.inner, got, tt.expected)
+		}
+	}
+}
+
+func TestRuleAffects(t *testing.T) {
+	rows := []string{
+		"[ 1] 22/tcp ALLOW IN Anywhere",
+		"[ 2] 22/tcp DENY IN 10.0.0.0/24",
+		"[ 3] Anywhere DENY IN 10.0.0.5",
+		"[ 4] 6000:6007/tcp ALLOW IN 192.168.1.0/24",
+		"[ 5] 53/udp ALLOW IN Anywhere",
+		"[ 6] 22/tcp (v6) ALLOW IN Anywhere (v6)",
+		"[ 7] 1.1.1.1 53 ALLOW OUT Anywhere on eth0 (out)",
+	}
+	rules := ParseRules(rows)
+
+	tests := []struct {
+		address, port, proto string
+		expected             []int
+	}{
+		{"10.0.0.5", "", "", []int{1, 2, 3, 5}},
+		{"10.0.0.0/16", "", "", []int{1, 2, 3, 5}},
+		{"192.168.1.7", "22", "", []int{1}},
+		{"10.0.0.5", "22", "tcp", []int{1, 2, 3}},
+		{"", "6005:6010", "", []int{3, 4}},
+		{"", "53", "tcp", []int{3, 7}},
+		{"1.1.1.1", "53", "", []int{5, 7}},
+		{"2001:db8::1", "", "", []int{6}},
+	}
+
+	for _, tt := range tests {
+		matches := []int{}
+		for _, rule := range rules {
+			if RuleAffects(rule, tt.address, tt.port, tt.proto) {
+				matches = append(matches, rule.Number)
+			}
+		}
+		if !reflect.DeepEqual(matches, tt.expected) {
+			t.Errorf("RuleAffects(%q, %q, %q): got %v, want %v", tt.address, tt.port, tt.proto, matches, tt.expected)
+		}
+	}
+}
+
+func TestParseUfwCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected domain.FormValues
+	}{
+		{
+			input:    "ufw allow 22/tcp",
+			expected: domain.FormValues{Port: "22", Protocol: "tcp", Action: "ALLOW IN"},
+		},
+		{
+			input:    "ufw allow OpenSSH",
+			expected: domain.FormValues{Port: "OpenSSH", Action: "ALLOW IN"},
+		},
+		{
+			input:    "ufw deny out on eth0 from any to 8.8.8.8 port 53 proto udp comment 'Block Google DNS'",
+			expected: domain.FormValues{To: "8.8.8.8", Port: "53", InterfaceOut: "eth0", Protocol: "udp", Action: "DENY OUT", Comment: "Block Google DNS"},
+		},
+		{
+			input:    "ufw route allow in on eth1 out on eth2 from 10.0.0.0/8 to 172.16.0.5 port 443 proto tcp",
+			expected: domain.FormValues{To: "172.16.0.5", Port: "443", Interface: "eth1", InterfaceOut: "eth2", Protocol: "tcp", Action: "ALLOW FWD", From: "10.0.0.0/8"},
+		},
+		{
+			input:    "ufw limit in from 10.0.0.0/8 port 1024 to any port 22",
+			expected: domain.FormValues{Port: "22", Action: "LIMIT IN", From: "10.0.0.0/8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			fv, err := ParseUfwCommand(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*fv, tt.expected) {
+				t.Errorf("got %+v, want %+v", *fv, tt.expected)
+			}
+		})
+	}
+
+	if _, err := ParseUfwCommand("ufw status"); err == nil {
+		t.Errorf("expected an error for a command that is not a rule")
+	}
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Services maps service names to ports, as listed in /etc/services.
+type Services struct {
+	names map[string]string   // "22/tcp" -> "ssh"
+	ports map[string][]string // "ssh" -> ["22/tcp"], aliases included
+}
+
+// ParseServices parses the content of /etc/services.
+func ParseServices(content string) *Services {
+	s := &Services{names: map[string]string{}, ports: map[string][]string{}}
+	for _, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		port, proto, found := strings.Cut(fields[1], "/")
+		if !found || (proto != "tcp" && proto != "udp") {
+			continue
+		}
+		if _, ok := s.names[fields[1]]; !ok {
+			s.names[fields[1]] = fields[0]
+		}
+		for _, name := range append([]string{fields[0]}, fields[2:]...) {
+			s.ports[name] = append(s.ports[name], port+"/"+proto)
+		}
+	}
+	return s
+}
+
+// Name returns the service using a port, or an empty string.
+func (s *Services) Name(port string, proto string) string {
+	if s == nil {
+		return ""
+	}
+	if proto != "" {
+		return s.names[port+"/"+proto]
+	}
+	if name, ok := s.names[port+"/tcp"]; ok {
+		return name
+	}
+	return s.names[port+"/udp"]
+}
+
+// Names returns the services of a port list such as 80,443, or an empty
+// string when none of them is known. Ranges are not named.
+func (s *Services) Names(ports string, proto string) string {
+	var names []string
+	found := false
+	for _, port := range strings.Split(ports, ",") {
+		name := s.Name(port, proto)
+		if name == "" {
+			name = port
+		} else {
+			found = true
+		}
+		names = append(names, name)
+	}
+	if !found {
+		return ""
+	}
+	return strings.Join(names, ",")
+}
+
+// Lookup returns the port of a service and its protocol, empty when it uses
+// the same port over TCP and UDP.
+func (s *Services) Lookup(name string) (string, string, bool) {
+	if s == nil || len(s.ports[name]) == 0 {
+		return "", "", false
+	}
+
+	entries := s.ports[name]
+	port, proto, _ := strings.Cut(entries[0], "/")
+	for _, entry := range entries[1:] {
+		if p, _, _ := strings.Cut(entry, "/"); p == port {
+			proto = ""
+		}
+	}
+	return port, proto, true
+}
+
+func (s *Services) uses(name string, proto string) bool {
+	for _, entry := range s.ports[name] {
+		if strings.HasSuffix(entry, "/"+proto) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve converts the service names of a port field, e.g. ssh or
+// http,https, into ports and the protocol they require. Numeric ports are
+// kept as they are.
+func (s *Services) Resolve(ports string, proto string) (string, string, error) {
+	if ports == "" || IsPortSpec(ports) {
+		return ports, proto, nil
+	}
+
+	var resolved []string
+	for _, item := range strings.Split(ports, ",") {
+		if IsPortSpec(item) {
+			resolved = append(resolved, item)
+			continue
+		}
+
+		port, serviceProto, ok := s.Lookup(item)
+		if !ok {
+			return "", "", fmt.Errorf("unknown service %q", item)
+		}
+		switch {
+		case proto == "":
+			proto = serviceProto
+		case serviceProto != "" && serviceProto != proto && !s.uses(item, proto):
+			return "", "", fmt.Errorf("%s does not use %s", item, proto)
+		}
+		resolved = append(resolved, port)
+	}
+	return strings.Join(resolved, ","), proto, nil
+}
+
+// Complete returns the service names completing the last item of a port field.
+func (s *Services) Complete(text string) []string {
+	if s == nil {
+		return nil
+	}
+
+	head, last := "", text
+	if idx := strings.LastIndex(text, ","); idx != -1 {
+		head, last = text[:idx+1], text[idx+1:]
+	}
+	if last == "" || IsPortSpec(last) {
+		return nil
+	}
+
+	var entries []string
+	for name := range s.ports {
+		if strings.HasPrefix(name, last) {
+			entries = append(entries, head+name)
+		}
+	}
+	sort.Strings(entries)
+	if len(entries) > 10 {
+		entries = entries[:10]
+	}
+	return entries
+}
+
+// AcceptPortInput accepts the characters of ports, ranges, lists and service names.
+func AcceptPortInput(text string, ch rune) bool {
+	return ch == ',' || ch == ':' || ch == '-' || ch == '_' ||
+		(ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+const servicesSample = `# Network services, Internet style
+ssh		22/tcp				# SSH Remote Login Protocol
+domain		53/tcp				# Domain Name Server
+domain		53/udp
+http		80/tcp		www		# WorldWideWeb HTTP
+https		443/tcp				# http protocol over TLS/SSL
+https		443/udp				# HTTP/3
+syslog		514/udp
+postgresql	5432/tcp	postgres	# PostgreSQL Database
+`
+
+func TestServices_Names(t *testing.T) {
+	services := ParseServices(servicesSample)
+
+	tests := []struct {
+		ports, proto, expected string
+	}{
+		{"22", "", "ssh"},
+		{"5432", "tcp", "postgresql"},
+		{"514", "tcp", ""},
+		{"514", "", "syslog"},
+		{"80,443,8080", "", "http,https,8080"},
+		{"6000:6007", "", ""},
+	}
+	for _, tt := range tests {
+		if got := services.Names(tt.ports, tt.proto); got != tt.expected {
+			t.Errorf("Names(%q, %q): got %q, want %q", tt.ports, tt.proto, got, tt.expected)
+		}
+	}
+
+	var missing *Services
+	if got := missing.Names("22", ""); got != "" {
+		t.Errorf("expected no name without services, got %q", got)
+	}
+}
+
+func TestServices_Resolve(t *testing.T) {
+	services := ParseServices(servicesSample)
+
+	tests := []struct {
+		ports, proto         string
+		expectedPorts        string
+		expectedProto        string
+		expectedErrorMessage string
+	}{
+		{"22", "", "22", "", ""},
+		{"ssh", "", "22", "tcp", ""},
+		{"postgres", "", "5432", "tcp", ""},
+		{"domain", "", "53", "", ""},
+		{"domain", "udp", "53", "udp", ""},
+		{"http,https", "", "80,443", "tcp", ""},
+		{"www,8080", "tcp", "80,8080", "tcp", ""},
+		{"ssh", "udp", "", "", "ssh does not use udp"},
+		{"ssh,syslog", "", "", "", `syslog does not use tcp`},
+		{"gopher", "", "", "", `unknown service "gopher"`},
+	}
+	for _, tt := range tests {
+		ports, proto, err := services.Resolve(tt.ports, tt.proto)
+		if tt.expectedErrorMessage != "" {
+			if err == nil || err.Error() != tt.expectedErrorMessage {
+				t.Errorf("Resolve(%q, %q): expected error %q, got %v", tt.ports, tt.proto, tt.expectedErrorMessage, err)
+			}
+			continue
+		}
+		if err != nil || ports != tt.expectedPorts || proto != tt.expectedProto {
+			t.Errorf("Resolve(%q, %q): got %q, %q, %v", tt.ports, tt.proto, ports, proto, err)
+		}
+	}
+}
+
+func TestServices_Complete(t *testing.T) {
+	services := ParseServices(servicesSample)
+
+	if got := services.Complete("po"); !reflect.DeepEqual(got, []string{"postgres", "postgresql"}) {
+		t.Errorf("got %v", got)
+	}
+	if got := services.Complete("ssh,ht"); !reflect.DeepEqual(got, []string{"ssh,http", "ssh,https"}) {
+		t.Errorf("got %v", got)
+	}
+	if got := services.Complete("22"); got != nil {
+		t.Errorf("expected no completion for a port, got %v", got)
+	}
+}
@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+var reDefaultPolicy = regexp.MustCompile(`(\w+) \((incoming|outgoing|routed)\)`)
+
+// PolicyKeys maps the direction of a rule to the name of its default policy.
+var PolicyKeys = map[string]string{"IN": "incoming", "OUT": "outgoing", "FWD": "routed"}
+
+// ParseDefaults extracts the default policies from `ufw status verbose`,
+// e.g. {"incoming": "deny", "outgoing": "allow", "routed": "disabled"}.
+func ParseDefaults(output string) map[string]string {
+	defaults := map[string]string{}
+	for _, m := range reDefaultPolicy.FindAllStringSubmatch(output, -1) {
+		defaults[m[2]] = m[1]
+	}
+	return defaults
+}
+
+// ParseDefaultsFile extracts the default policies from /etc/default/ufw, which
+// is the only place they can be read from while ufw is inactive.
+func ParseDefaultsFile(content string) map[string]string {
+	keys := map[string]string{
+		"DEFAULT_INPUT_POLICY":   "incoming",
+		"DEFAULT_OUTPUT_POLICY":  "outgoing",
+		"DEFAULT_FORWARD_POLICY": "routed",
+	}
+	policies := map[string]string{"ACCEPT": "allow", "DROP": "deny", "REJECT": "reject"}
+
+	defaults := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || keys[key] == "" {
+			continue
+		}
+		if policy, ok := policies[strings.Trim(value, `"`)]; ok {
+			defaults[keys[key]] = policy
+		}
+	}
+	return defaults
+}
+
+// ParseAppPorts extracts the ports of a `ufw app info` output. The protocol
+// is only returned when every port of the profile shares it.
+func ParseAppPorts(output string) (ports string, proto string) {
+	var values []string
+	protocols := map[string]bool{}
+	inPorts := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "Port:" || trimmed == "Ports:" {
+			inPorts = true
+			continue
+		}
+		if !inPorts || trimmed == "" {
+			continue
+		}
+		value, p := splitProtocol(trimmed)
+		values = append(values, value)
+		protocols[p] = true
+	}
+
+	if len(protocols) == 1 {
+		for p := range protocols {
+			proto = p
+		}
+	}
+	return strings.Join(values, ","), proto
+}
+
+// ParseSSHConnection turns the SSH_CONNECTION environment variable
+// ("client_ip client_port server_ip server_port") into the inbound packet of the session.
+func ParseSSHConnection(value string) *domain.Packet {
+	fields := strings.Fields(value)
+	if len(fields) != 4 {
+		return nil
+	}
+	// The client address ends up in commands finding the session interface
+	for _, address := range []string{fields[0], fields[2]} {
+		if _, err := netip.ParseAddr(address); err != nil {
+			return nil
+		}
+	}
+	return &domain.Packet{
+		Direction: "IN",
+		From:      fields[0],
+		FromPort:  fields[1],
+		To:        fields[2],
+		Port:      fields[3],
+		Protocol:  "tcp",
+	}
+}
+
+// RuleFromForm builds the rules ufw would add for the given form values, one
+// per address family when neither address pins it.
+func RuleFromForm(fv domain.FormValues) []domain.Rule {
+	tokens := strings.Fields(strings.ToUpper(strings.ReplaceAll(fv.Action, "-", " ")))
+	rule := domain.Rule{
+		To:        fv.To,
+		ToPort:    fv.Port,
+		From:      fv.From,
+		Protocol:  fv.Protocol,
+		Interface: fv.Interface,
+		Comment:   fv.Comment,
+		Direction: "IN",
+	}
+	if len(tokens) > 0 {
+		rule.Action = tokens[0]
+	}
+	if len(tokens) > 1 {
+		rule.Direction = tokens[1]
+	}
+	switch rule.Direction {
+	case "FWD":
+		rule.InterfaceOut = fv.InterfaceOut
+	case "OUT":
+		rule.Interface = fv.InterfaceOut
+	}
+	if rule.To == "" || rule.To == "Anywhere" {
+		rule.To = "any"
+	}
+	if rule.From == "" || rule.From == "Anywhere" {
+		rule.From = "any"
+	}
+
+	for _, address := range []string{rule.To, rule.From} {
+		if prefix, err := parsePrefix(address); err == nil {
+			rule.V6 = prefix.Addr().Is6()
+			return []domain.Rule{rule}
+		}
+	}
+
+	v6 := rule
+	v6.V6 = true
+	return []domain.Rule{rule, v6}
+}
+
+// SpliceRules returns a copy of rules where the rules numbered in numbers are
+// removed and replaced by with, which is appended when numbers is empty.
+func SpliceRules(rules []domain.Rule, numbers []int, with []domain.Rule) []domain.Rule {
+	removed := map[int]bool{}
+	for _, n := range numbers {
+		removed[n] = true
+	}
+
+	var spliced []domain.Rule
+	inserted := false
+	for _, rule := range rules {
+		if !removed[rule.Number] {
+			spliced = append(spliced, rule)
+			continue
+		}
+		if !inserted {
+			spliced = append(spliced, with...)
+			inserted = true
+		}
+	}
+	if !inserted {
+		spliced = append(spliced, with...)
+	}
+
+	return spliced
+}
+
+func isV6(address string) bool {
+	prefix, err := parsePrefix(address)
+	return err == nil && prefix.Addr().Is6()
+}
+
+func packetIsV6(p domain.Packet) bool {
+	return isV6(p.From) || isV6(p.To)
+}
+
+// ParseRouteInterface extracts the interface of an `ip route get` output,
+// e.g. "203.0.113.4 via 192.168.0.254 dev eth0 src 192.168.0.1 uid 0".
+func ParseRouteInterface(output string) string {
+	fields := strings.Fields(output)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "dev" {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// RuleMatches reports whether the rule applies to the packet.
+func RuleMatches(rule domain.Rule, p domain.Packet) bool {
+	if rule.Direction != p.Direction || rule.V6 != packetIsV6(p) {
+		return false
+	}
+	if rule.Protocol != "" && p.Protocol != "" && rule.Protocol != p.Protocol {
+		return false
+	}
+
+	return fieldContains(rule.Interface, p.Interface) &&
+		fieldContains(rule.InterfaceOut, p.InterfaceOut) &&
+		AddressContains(rule.To, orAny(p.To)) &&
+		AddressContains(rule.From, orAny(p.From)) &&
+		PortContains(rule.ToPort, p.Port) &&
+		PortContains(rule.FromPort, p.FromPort)
+}
+
+func orAny(address string) string {
+	if address == "" {
+		return "any"
+	}
+	return address
+}
+
+// Simulate walks the rules in evaluation order and returns the action of the
+// first one matching the packet, or the default policy when none does.
+func Simulate(rules []domain.Rule, defaults map[string]string, p domain.Packet) (string, *domain.Rule) {
+	for i := range rules {
+		if RuleMatches(rules[i], p) {
+			return rules[i].Action, &rules[i]
+		}
+	}
+	return defaultAction(defaults, p.Direction), nil
+}
+
+// SimulateSession is Simulate for the packet of a session whose incoming
+// interface may be unknown. Rules tied to an interface then possibly match
+// it: those blocking are assumed to, those allowing cannot be relied on.
+func SimulateSession(rules []domain.Rule, defaults map[string]string, p domain.Packet) (string, *domain.Rule) {
+	if p.Interface != "" {
+		return Simulate(rules, defaults, p)
+	}
+	for i := range rules {
+		rule := rules[i]
+		if rule.Interface == "" {
+			if RuleMatches(rule, p) {
+				return rule.Action, &rules[i]
+			}
+			continue
+		}
+		rule.Interface = ""
+		if RuleMatches(rule, p) && !IsAllowed(rule.Action) {
+			return rule.Action, &rules[i]
+		}
+	}
+	return defaultAction(defaults, p.Direction), nil
+}
+
+func defaultAction(defaults map[string]string, direction string) string {
+	policy := defaults[PolicyKeys[direction]]
+	if policy == "" {
+		policy = "deny"
+	}
+	return strings.ToUpper(policy)
+}
+
+// IsAllowed reports whether an action lets the traffic through.
+func IsAllowed(action string) bool {
+	return action == "ALLOW" || action == "LIMIT"
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestParseDefaults(t *testing.T) {
+	output := "Status: active\nLogging: on (low)\nDefault: deny (incoming), allow (outgoing), disabled (routed)\nNew profiles: skip\n"
+	expected := map[string]string{"incoming": "deny", "outgoing": "allow", "routed": "disabled"}
+	if got := ParseDefaults(output); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+
+	file := "IPV6=yes\nDEFAULT_INPUT_POLICY=\"DROP\"\nDEFAULT_OUTPUT_POLICY=\"ACCEPT\"\nDEFAULT_FORWARD_POLICY=\"REJECT\"\n"
+	expected = map[string]string{"incoming": "deny", "outgoing": "allow", "routed": "reject"}
+	if got := ParseDefaultsFile(file); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
+
+func TestParseAppPorts(t *testing.T) {
+	tests := []struct {
+		output string
+		ports  string
+		proto  string
+	}{
+		{"Profile: OpenSSH\nTitle: Secure shell server\n\nPort:\n  22/tcp\n", "22", "tcp"},
+		{"Profile: Apache Full\n\nPorts:\n  80,443/tcp\n", "80,443", "tcp"},
+		{"Profile: Samba\n\nPorts:\n  137,138/udp\n  139,445/tcp\n", "137,138,139,445", ""},
+	}
+
+	for _, tt := range tests {
+		ports, proto := ParseAppPorts(tt.output)
+		if ports != tt.ports || proto != tt.proto {
+			t.Errorf("got %q %q, want %q %q", ports, proto, tt.ports, tt.proto)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	rules := ParseRules([]string{
+		"[ 1] 22/tcp DENY IN 10.0.0.0/8",
+		"[ 2] 22/tcp ALLOW IN Anywhere",
+		"[ 3] 53 ALLOW OUT Anywhere on eth0 (out)",
+		"[ 4] 22/tcp (v6) DENY IN Anywhere (v6)",
+	})
+	defaults := map[string]string{"incoming": "deny", "outgoing": "reject"}
+
+	tests := []struct {
+		name   string
+		packet domain.Packet
+		action string
+		rule   int
+	}{
+		{"denied subnet", domain.Packet{Direction: "IN", From: "10.1.2.3", To: "192.168.0.1", Port: "22", Protocol: "tcp"}, "DENY", 1},
+		{"allowed ssh", domain.Packet{Direction: "IN", From: "203.0.113.4", To: "192.168.0.1", Port: "22", Protocol: "tcp"}, "ALLOW", 2},
+		{"default incoming", domain.Packet{Direction: "IN", From: "203.0.113.4", To: "192.168.0.1", Port: "80", Protocol: "tcp"}, "DENY", 0},
+		{"outgoing on interface", domain.Packet{Direction: "OUT", To: "1.1.1.1", Port: "53", Protocol: "udp", Interface: "eth0"}, "ALLOW", 3},
+		{"outgoing on other interface", domain.Packet{Direction: "OUT", To: "1.1.1.1", Port: "53", Protocol: "udp", Interface: "wg0"}, "REJECT", 0},
+		{"v6 only matches v6 rules", domain.Packet{Direction: "IN", From: "2001:db8::1", To: "2001:db8::2", Port: "22", Protocol: "tcp"}, "DENY", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, rule := Simulate(rules, defaults, tt.packet)
+			if action != tt.action {
+				t.Errorf("action: got %s, want %s", action, tt.action)
+			}
+			number := 0
+			if rule != nil {
+				number = rule.Number
+			}
+			if number != tt.rule {
+				t.Errorf("rule: got %d, want %d", number, tt.rule)
+			}
+		})
+	}
+}
+
+func TestRuleFromForm(t *testing.T) {
+	rules := RuleFromForm(domain.FormValues{Port: "22", Protocol: "tcp", Action: "deny-in"})
+	if len(rules) != 2 || rules[0].V6 || !rules[1].V6 {
+		t.Fatalf("expected a v4 and a v6 rule, got %+v", rules)
+	}
+	if rules[0].Action != "DENY" || rules[0].Direction != "IN" || rules[0].To != "any" || rules[0].From != "any" {
+		t.Errorf("unexpected rule %+v", rules[0])
+	}
+
+	rules = RuleFromForm(domain.FormValues{From: "10.0.0.0/8", Action: "ALLOW IN"})
+	if len(rules) != 1 || rules[0].V6 {
+		t.Errorf("expected a single v4 rule, got %+v", rules)
+	}
+
+	rules = RuleFromForm(domain.FormValues{To: "1.1.1.1", InterfaceOut: "eth0", Action: "ALLOW OUT"})
+	if len(rules) != 1 || rules[0].Interface != "eth0" || rules[0].Direction != "OUT" {
+		t.Errorf("expected a single outbound rule on eth0, got %+v", rules)
+	}
+}
+
+func TestParseSSHConnection(t *testing.T) {
+	p := ParseSSHConnection("203.0.113.4 51234 192.168.0.1 22")
+	expected := domain.Packet{Direction: "IN", From: "203.0.113.4", FromPort: "51234", To: "192.168.0.1", Port: "22", Protocol: "tcp"}
+	if p == nil || *p != expected {
+		t.Errorf("got %+v, want %+v", p, expected)
+	}
+	if ParseSSHConnection("") != nil {
+		t.Errorf("expected nil outside of an SSH session")
+	}
+	if ParseSSHConnection("$(reboot) 51234 192.168.0.1 22") != nil {
+		t.Errorf("expected nil for an invalid client address")
+	}
+}
+
+func TestParseRouteInterface(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.4 via 192.168.0.254 dev eth0 src 192.168.0.1 uid 0\n    cache \n": "eth0",
+		"local 127.0.0.1 dev lo table local src 127.0.0.1 uid 0":                     "lo",
+		"RTNETLINK answers: Network is unreachable":                                  "",
+	}
+	for output, expected := range tests {
+		if got := ParseRouteInterface(output); got != expected {
+			t.Errorf("%q: got %q, want %q", output, got, expected)
+		}
+	}
+}
+
+func TestSimulateSession(t *testing.T) {
+	defaults := map[string]string{"incoming": "deny"}
+	rules := ParseRules([]string{
+		"[ 1] 22 on eth0 DENY IN Anywhere",
+		"[ 2] 22/tcp ALLOW IN Anywhere",
+	})
+	session := *ParseSSHConnection("203.0.113.4 51234 192.168.0.1 22")
+
+	// The session may come in through eth0
+	if action, rule := SimulateSession(rules, defaults, session); action != "DENY" || rule == nil || rule.Number != 1 {
+		t.Errorf("an unknown interface should be blocked by rule 1, got %s", action)
+	}
+
+	session.Interface = "eth1"
+	if action, _ := SimulateSession(rules, defaults, session); action != "ALLOW" {
+		t.Errorf("a session on eth1 should be allowed by rule 2, got %s", action)
+	}
+	session.Interface = "eth0"
+	if action, _ := SimulateSession(rules, defaults, session); action != "DENY" {
+		t.Errorf("a session on eth0 should be denied, got %s", action)
+	}
+
+	// Allowing on an interface the session may not come in through is not enough
+	allowOnly := ParseRules([]string{"[ 1] 22 on eth0 ALLOW IN Anywhere"})
+	session.Interface = ""
+	if action, _ := SimulateSession(allowOnly, defaults, session); action != "DENY" {
+		t.Errorf("expected the default policy, got %s", action)
+	}
+}
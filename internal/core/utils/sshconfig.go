@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+)
+
+// ParseSSHConfig returns the host aliases of an ssh client configuration
+// such as ~/.ssh/config, in order. Patterns cannot be connected to and are
+// left out.
+func ParseSSHConfig(content string) []string {
+	var hosts []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Keywords are separated from their arguments by spaces or an equal sign
+		keyword, args, _ := strings.Cut(strings.Replace(line, "=", " ", 1), " ")
+		if !strings.EqualFold(keyword, "Host") {
+			continue
+		}
+		for _, alias := range strings.Fields(args) {
+			if alias == "" || strings.ContainsAny(alias, "*?!") || slices.Contains(hosts, alias) {
+				continue
+			}
+			hosts = append(hosts, alias)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSSHConfig(t *testing.T) {
+	content := `
+# Firewalls
+Host fw1 fw2
+    HostName 10.0.0.1
+    User admin
+
+Host *.internal !bastion
+    ProxyJump bastion
+
+host=bastion
+  HostName bastion.example.com
+
+Host db1 fw1
+Match host fw3
+Host gw?
+`
+
+	expected := []string{"fw1", "fw2", "bastion", "db1"}
+	if hosts := ParseSSHConfig(content); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("got %v, want %v", hosts, expected)
+	}
+}
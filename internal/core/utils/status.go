@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+var reStatusField = regexp.MustCompile(`(?m)^(Status|Logging):\s*(.*)$`)
+
+// ParseStatus extracts the state, logging level and default policies from
+// `ufw status verbose`.
+func ParseStatus(output string) domain.Status {
+	status := domain.Status{Defaults: ParseDefaults(output)}
+	for _, m := range reStatusField.FindAllStringSubmatch(output, -1) {
+		value := strings.TrimSpace(m[2])
+		switch m[1] {
+		case "Status":
+			status.Active = value == "active"
+		case "Logging":
+			status.Logging = value
+		}
+	}
+	return status
+}
+
+// ParseLogLevel extracts the logging level from /etc/ufw/ufw.conf, the way
+// `ufw status verbose` displays it, e.g. "on (low)" or "off".
+func ParseLogLevel(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || key != "LOGLEVEL" {
+			continue
+		}
+		if level := strings.Trim(value, `"`); level != "off" {
+			return "on (" + level + ")"
+		}
+		return "off"
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected domain.Status
+	}{
+		{
+			"Status: active\nLogging: on (low)\nDefault: deny (incoming), allow (outgoing), disabled (routed)\nNew profiles: skip\n",
+			domain.Status{Active: true, Logging: "on (low)", Defaults: map[string]string{"incoming": "deny", "outgoing": "allow", "routed": "disabled"}},
+		},
+		{"Status: inactive\n", domain.Status{Defaults: map[string]string{}}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseStatus(tt.output); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: got %+v, want %+v", tt.output, got, tt.expected)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"ENABLED=no\nLOGLEVEL=low\n", "on (low)"},
+		{"LOGLEVEL=\"high\"\n", "on (high)"},
+		{"LOGLEVEL=off\n", "off"},
+		{"ENABLED=no\n", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseLogLevel(tt.content); got != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.content, got, tt.expected)
+		}
+	}
+}
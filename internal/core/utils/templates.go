@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/*.yaml
+var builtinTemplates embed.FS
+
+// Template describes a set of rules for a server role, with parameters
+// written as {{name}} in the rules.
+type Template struct {
+	Name        string          `yaml:"name"`
+	Description string          `yaml:"description"`
+	Params      []TemplateParam `yaml:"params"`
+	Rules       []TemplateRule  `yaml:"rules"`
+}
+
+type TemplateParam struct {
+	Name     string `yaml:"name"`
+	Label    string `yaml:"label"`
+	Default  string `yaml:"default"`
+	Required bool   `yaml:"required"`
+}
+
+type TemplateRule struct {
+	Action       string `yaml:"action"`
+	To           string `yaml:"to"`
+	Port         string `yaml:"port"`
+	Protocol     string `yaml:"protocol"`
+	From         string `yaml:"from"`
+	Interface    string `yaml:"interface"`
+	InterfaceOut string `yaml:"interface_out"`
+	Comment      string `yaml:"comment"`
+}
+
+var (
+	templateActionRegexp = regexp.MustCompile(`^(?i)(allow|deny|reject|limit)[ -](in|out|fwd)$`)
+	placeholderRegexp    = regexp.MustCompile(`{{\s*([A-Za-z0-9_-]+)\s*}}`)
+)
+
+// ParseTemplate reads a template written in YAML.
+func ParseTemplate(data []byte) (Template, error) {
+	var tpl Template
+	if err := yaml.Unmarshal(data, &tpl); err != nil {
+		return tpl, err
+	}
+	if tpl.Name == "" {
+		return tpl, fmt.Errorf("missing template name")
+	}
+	if len(tpl.Rules) == 0 {
+		return tpl, fmt.Errorf("%s: no rule", tpl.Name)
+	}
+
+	declared := map[string]bool{}
+	for _, param := range tpl.Params {
+		if param.Name == "" {
+			return tpl, fmt.Errorf("%s: parameter without a name", tpl.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	for i, rule := range tpl.Rules {
+		if !templateActionRegexp.MatchString(rule.Action) {
+			return tpl, fmt.Errorf("%s: rule %d: invalid action %q", tpl.Name, i+1, rule.Action)
+		}
+		for _, value := range []string{rule.To, rule.Port, rule.Protocol, rule.From, rule.Interface, rule.InterfaceOut, rule.Comment} {
+			for _, match := range placeholderRegexp.FindAllStringSubmatch(value, -1) {
+				if !declared[match[1]] {
+					return tpl, fmt.Errorf("%s: rule %d: unknown parameter %q", tpl.Name, i+1, match[1])
+				}
+			}
+		}
+	}
+	return tpl, nil
+}
+
+// BuiltinTemplates returns the templates shipped with tufw.
+func BuiltinTemplates() []Template {
+	entries, _ := builtinTemplates.ReadDir("templates")
+
+	var templates []Template
+	for _, entry := range entries {
+		data, err := builtinTemplates.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		if tpl, err := ParseTemplate(data); err == nil {
+			templates = append(templates, tpl)
+		}
+	}
+	return templates
+}
+
+// Expand fills the template parameters, using their defaults for missing
+// values, and returns the rules to add.
+func (tpl Template) Expand(values map[string]string) ([]domain.FormValues, error) {
+	filled := map[string]string{}
+	for _, param := range tpl.Params {
+		value := strings.TrimSpace(values[param.Name])
+		if value == "" {
+			value = param.Default
+		}
+		if value == "" && param.Required {
+			return nil, fmt.Errorf("%s is required", paramLabel(param))
+		}
+		filled[param.Name] = value
+	}
+
+	fill := func(value string) string {
+		return placeholderRegexp.ReplaceAllStringFunc(value, func(match string) string {
+			return filled[placeholderRegexp.FindStringSubmatch(match)[1]]
+		})
+	}
+
+	rules := make([]domain.FormValues, len(tpl.Rules))
+	for i, rule := range tpl.Rules {
+		rules[i] = domain.FormValues{
+			To:           fill(rule.To),
+			Port:         fill(rule.Port),
+			Interface:    fill(rule.Interface),
+			InterfaceOut: fill(rule.InterfaceOut),
+			Protocol:     fill(rule.Protocol),
+			Action:       strings.ToUpper(strings.ReplaceAll(rule.Action, "-", " ")),
+			From:         fill(rule.From),
+			Comment:      fill(rule.Comment),
+		}
+	}
+	return rules, nil
+}
+
+func paramLabel(param TemplateParam) string {
+	if param.Label != "" {
+		return param.Label
+	}
+	return param.Name
+}
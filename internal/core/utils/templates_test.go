@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	names := map[string]bool{}
+	for _, tpl := range BuiltinTemplates() {
+		names[tpl.Name] = true
+	}
+
+	for _, name := range []string{"Web server", "SSH from bastion only", "PostgreSQL", "WireGuard", "DNS server", "Mail server"} {
+		if !names[name] {
+			t.Errorf("missing built-in template %q", name)
+		}
+	}
+}
+
+func TestTemplate_Expand(t *testing.T) {
+	tpl, err := ParseTemplate([]byte(`
+name: SSH from bastion only
+params:
+  - name: bastion
+    required: true
+  - name: port
+    default: "22"
+rules:
+  - action: allow-in
+    port: "{{port}}"
+    protocol: tcp
+    from: "{{ bastion }}"
+    comment: SSH from {{bastion}}
+  - action: DENY IN
+    port: "{{port}}"
+    protocol: tcp
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rules, err := tpl.Expand(map[string]string{"bastion": "10.0.0.4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []domain.FormValues{
+		{Port: "22", Protocol: "tcp", Action: "ALLOW IN", From: "10.0.0.4", Comment: "SSH from 10.0.0.4"},
+		{Port: "22", Protocol: "tcp", Action: "DENY IN"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("got %+v, want %+v", rules, expected)
+	}
+
+	if _, err := tpl.Expand(map[string]string{"port": "2222"}); err == nil {
+		t.Error("expected an error for a missing required parameter")
+	}
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	invalid := []string{
+		"rules:\n  - action: ALLOW IN\n",
+		"name: Empty\n",
+		"name: Bad action\nrules:\n  - action: PERMIT IN\n",
+		"name: Unknown parameter\nrules:\n  - action: ALLOW IN\n    port: \"{{port}}\"\n",
+		"name: [broken",
+	}
+	for _, data := range invalid {
+		if _, err := ParseTemplate([]byte(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
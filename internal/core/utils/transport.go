@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// transportPrograms are the programs a transport can run the commands through.
+var transportPrograms = []string{"ssh", "sudo", "nsenter", "docker", "lxc"}
+
+var (
+	// reArgument matches the arguments a transport accepts, host and
+	// container names or options, none of which the shell interprets but
+	// the brackets of IPv6 addresses.
+	reArgument = regexp.MustCompile(`^[\w@.:%+=,/\[\]-]+$`)
+	reBare     = regexp.MustCompile(`^[\w@.:%+=,/-]+$`)
+)
+
+// Transport runs the commands of tufw through a prefix such as "ssh host",
+// "sudo", "nsenter -t PID -n", "docker exec container" or "lxc exec
+// container". The zero value runs them locally.
+type Transport struct {
+	Prefix string
+}
+
+// transport is the one Shellout runs the commands through.
+var transport Transport
+
+// ParseTransport checks a transport prefix, empty meaning local.
+func ParseTransport(prefix string) (Transport, error) {
+	words := strings.Fields(prefix)
+	if len(words) == 0 {
+		return Transport{}, nil
+	}
+	if !slices.Contains(transportPrograms, words[0]) {
+		return Transport{}, fmt.Errorf("%q is not one of %s", words[0], strings.Join(transportPrograms, ", "))
+	}
+	for _, word := range words[1:] {
+		if !reArgument.MatchString(word) {
+			return Transport{}, fmt.Errorf("%q holds characters the shell would interpret", word)
+		}
+	}
+	switch words[0] {
+	case "ssh":
+		if len(words) < 2 {
+			return Transport{}, fmt.Errorf("ssh needs a host")
+		}
+	case "docker", "lxc":
+		if len(words) < 3 || words[1] != "exec" {
+			return Transport{}, fmt.Errorf("expected %s exec followed by a container", words[0])
+		}
+	}
+	return Transport{Prefix: strings.Join(words, " ")}, nil
+}
+
+// Local reports whether the commands run on the local host.
+func (t Transport) Local() bool {
+	return t.Prefix == ""
+}
+
+// Remote reports whether the commands run on another host over ssh.
+func (t Transport) Remote() bool {
+	return strings.HasPrefix(t.Prefix, "ssh ")
+}
+
+func (t Transport) String() string {
+	if t.Local() {
+		return "local"
+	}
+	return t.Prefix
+}
+
+// nonInteractiveFlags keep the programs from prompting for a password or
+// a host key, which would garble the screen. They fail instead.
+var nonInteractiveFlags = map[string]string{
+	"ssh":  "-o BatchMode=yes -n",
+	"sudo": "-n",
+}
+
+// Wrap returns the command running command through the transport.
+func (t Transport) Wrap(command string) string {
+	if t.Local() {
+		return command
+	}
+
+	words := strings.Fields(t.Prefix)
+	if flags, ok := nonInteractiveFlags[words[0]]; ok {
+		words = slices.Concat(words[:1], strings.Fields(flags), words[1:])
+	}
+	for i, word := range words {
+		if !reBare.MatchString(word) {
+			words[i] = shellQuote(word)
+		}
+	}
+	prefix := strings.Join(words, " ")
+
+	wrapped := "bash -c " + shellQuote(command)
+	switch {
+	case t.Remote():
+		// ssh joins its arguments into a command line the remote shell parses again
+		return prefix + " " + shellQuote(wrapped)
+	case strings.HasPrefix(prefix, "lxc ") && !strings.HasSuffix(prefix, " --"):
+		return prefix + " -- " + wrapped
+	}
+	return prefix + " " + wrapped
+}
+
+// Shellout runs a command through t rather than the transport set.
+func (t Transport) Shellout(command string) (string, string, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command("bash", "-c", t.Wrap(command))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// SetTransport makes Shellout run the commands through t.
+func SetTransport(t Transport) {
+	transport = t
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
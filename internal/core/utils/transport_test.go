@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseTransport(t *testing.T) {
+	valid := map[string]string{
+		"":                         "",
+		"  ssh   admin@fw1 ":       "ssh admin@fw1",
+		"ssh -p 2222 fw1":          "ssh -p 2222 fw1",
+		"sudo":                     "sudo",
+		"nsenter -t 1234 -n":       "nsenter -t 1234 -n",
+		"docker exec -u 0 gateway": "docker exec -u 0 gateway",
+		"lxc exec router --":       "lxc exec router --",
+		"ssh admin@[fe80::1%eth0]": "ssh admin@[fe80::1%eth0]",
+	}
+	for input, prefix := range valid {
+		transport, err := ParseTransport(input)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", input, err)
+		}
+		if transport.Prefix != prefix {
+			t.Errorf("%q: got %q, want %q", input, transport.Prefix, prefix)
+		}
+	}
+
+	for _, input := range []string{"ssh", "telnet fw1", "docker gateway", "lxc exec", "bash -c",
+		"ssh fw1;reboot", "ssh $(reboot)", "docker exec 'gateway'", "sudo -u root&&reboot", "nsenter -t `reboot`"} {
+		if _, err := ParseTransport(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestTransportWrap(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"", "ufw status | grep 'ALLOW'"},
+		{"sudo", `sudo -n bash -c 'ufw status | grep '\''ALLOW'\'''`},
+		{"sudo -u root", `sudo -n -u root bash -c 'ufw status | grep '\''ALLOW'\'''`},
+		{"docker exec gateway", `docker exec gateway bash -c 'ufw status | grep '\''ALLOW'\'''`},
+		{"lxc exec router", `lxc exec router -- bash -c 'ufw status | grep '\''ALLOW'\'''`},
+		{"lxc exec router --", `lxc exec router -- bash -c 'ufw status | grep '\''ALLOW'\'''`},
+		{"ssh [fe80::1]", `ssh -o BatchMode=yes -n '[fe80::1]' 'bash -c '\''ufw status | grep '\''\'\'''\''ALLOW'\''\'\'''\'''\'''`},
+		{"ssh fw1", `ssh -o BatchMode=yes -n fw1 'bash -c '\''ufw status | grep '\''\'\'''\''ALLOW'\''\'\'''\'''\'''`},
+	}
+
+	for _, tt := range tests {
+		transport, _ := ParseTransport(tt.prefix)
+		if got := transport.Wrap("ufw status | grep 'ALLOW'"); got != tt.expected {
+			t.Errorf("%q: got %s, want %s", tt.prefix, got, tt.expected)
+		}
+	}
+}
+
+func TestTransportWrap_Shell(t *testing.T) {
+	// env stands for the program of a transport, running its arguments as is
+	command := `printf '%s\n' "it's" | tr a-z A-Z`
+	wrapped := Transport{Prefix: "env"}.Wrap(command)
+	out, err := exec.Command("bash", "-c", wrapped).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "IT'S\n" {
+		t.Errorf("got %q", out)
+	}
+
+	// ssh hands its arguments to the remote shell, parsing them once more
+	wrapped = strings.Replace(Transport{Prefix: "ssh fw1"}.Wrap(command), "ssh -o BatchMode=yes -n fw1", "bash -c", 1)
+	out, err = exec.Command("bash", "-c", wrapped).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "IT'S\n" {
+		t.Errorf("ssh: got %q", out)
+	}
+}
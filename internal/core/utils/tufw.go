@@ -1,9 +1,7 @@
 package utils
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,7 +10,7 @@ import (
 )
 
 func FormatUfwRule(row string) string {
-	row = strings.TrimSpace(row)
+	row = strings.TrimSpace(strings.ReplaceAll(row, "(out)", ""))
 	row = regexp.MustCompile(`\s+`).ReplaceAllString(row, " ")
 	row = regexp.MustCompile(`\[ ?(\d+)\]`).ReplaceAllString(row, "[$1]")
 
@@ -45,19 +43,25 @@ func FormatUfwRule(row string) string {
 			outIface = m[2]
 		}
 		row = re.ReplaceAllString(row, "")
+	} else if loc := regexp.MustCompile(`\b(ALLOW|DENY|REJECT|LIMIT) FWD\b`).FindStringIndex(row); loc != nil {
+		// Case 2: route rule, the interface before the action is the outgoing one
+		onRe := regexp.MustCompile(`\bon\s+([^\s]+)`)
+		for _, m := range onRe.FindAllStringSubmatchIndex(row, -1) {
+			if m[0] < loc[0] {
+				outIface = row[m[2]:m[3]]
+			} else {
+				inIface = row[m[2]:m[3]]
+			}
+		}
+		row = onRe.ReplaceAllString(row, "")
 	} else {
-		// Case 2: generic "on"
+		// Case 3: generic "on"
 		onRe := regexp.MustCompile(`\bon\s+([^\s]+)`)
 		matches := onRe.FindAllStringSubmatch(row, -1)
 		// Basic rule with an interface IN
 		if len(matches) > 0 {
 			inIface = matches[0][1]
 		}
-		// FWD rule with interface IN and OUT (overriding above)
-		if len(matches) > 1 {
-			inIface = matches[1][1]
-			outIface = matches[0][1]
-		}
 		row = onRe.ReplaceAllString(row, "")
 	}
 
@@ -67,10 +71,10 @@ func FormatUfwRule(row string) string {
 		return fmt.Sprintf("%s %s", index, row)
 	}
 
-	// --- Find ALLOW / DENY ---
+	// --- Find ALLOW / DENY / REJECT / LIMIT ---
 	actionIdx := -1
 	for i, t := range tokens {
-		if t == "ALLOW" || t == "DENY" {
+		if t == "ALLOW" || t == "DENY" || t == "REJECT" || t == "LIMIT" {
 			actionIdx = i
 			break
 		}
@@ -126,17 +130,20 @@ func FormatUfwRule(row string) string {
 			port = parts[1]
 		}
 	}
-	if proto != "" {
-		toPart += "/" + proto
-	}
-
 	if port == "-" {
-		// If toPart is a pure number, it's actually the port
-		if regexp.MustCompile(`^\d+$`).MatchString(toPart) {
-			port = toPart
+		// If toPart is a pure port (list or range), it's actually the port
+		if m := regexp.MustCompile(`^(\d[\d,:]*)(?:/(tcp|udp))?$`).FindStringSubmatch(toPart); m != nil {
+			port = m[1]
 			toPart = ""
+			if m[2] != "" {
+				toPart = "Anywhere"
+				proto = m[2]
+			}
 		}
 	}
+	if proto != "" {
+		toPart += "/" + proto
+	}
 
 	// --- Attach interfaces ---
 	if outIface != "" {
@@ -152,29 +159,9 @@ func FormatUfwRule(row string) string {
 	return formatted
 }
 
+// Shellout runs a command through the transport set, locally by default.
 func Shellout(command string) (string, string, error) {
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd := exec.Command("bash", "-c", command)
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	return stdout.String(), stderr.String(), err
-}
-
-func ValidatePort(text string, ch rune) bool {
-	_, err := strconv.Atoi(text)
-	return err == nil
-}
-
-func ParseIPAddress(input string) string {
-	r := regexp.MustCompile(`(([0-9]{1,3}\.){3}[0-9]{1,3})(/[0-9]{1,2})?`)
-	matches := r.FindStringSubmatch(input)
-	value := ""
-	if len(matches) > 0 {
-		value = matches[0]
-	}
-	return value
+	return transport.Shellout(command)
 }
 
 func ParseProtocol(inputs ...string) string {
@@ -190,17 +177,6 @@ func ParseProtocol(inputs ...string) string {
 	return value
 }
 
-func ParsePort(input string) string {
-	r := regexp.MustCompile(`([0-9]*)(/[a-z]{3})?`)
-	value := ""
-	matches := r.FindStringSubmatch(input)
-	if len(matches) > 0 {
-		value = matches[1]
-	}
-
-	return value
-}
-
 func ParseInterfaceIndex(input string, interfaces []string) int {
 	for i, interfaceValue := range interfaces {
 		if input == interfaceValue {
@@ -269,9 +245,6 @@ func FillCell(row string) *domain.CellValues {
 	if proto != "" {
 		toDisplay = fmt.Sprintf("%s/%s", address, proto)
 	}
-	if ifaceOut != "" {
-		toDisplay = fmt.Sprintf("%s_on_%s", toDisplay, ifaceOut)
-	}
 
 	if toDisplay == "" {
 		toDisplay = "-"
@@ -304,9 +277,17 @@ func FillCell(row string) *domain.CellValues {
 	}
 
 	fromField, _, ifaceIn := ParseFromOrTo(fromField)
-	fromDisplay := fromField
-	if ifaceIn != "" {
-		fromDisplay = fmt.Sprintf("%s_on_%s", fromField, ifaceIn)
+
+	// --- Interfaces, following the direction of the rule ---
+	switch {
+	case strings.HasSuffix(actionField, "-OUT"):
+		if ifaceOut == "" {
+			ifaceOut, ifaceIn = ifaceIn, ""
+		}
+	case !strings.HasSuffix(actionField, "-FWD"):
+		if ifaceIn == "" {
+			ifaceIn, ifaceOut = ifaceOut, ""
+		}
 	}
 
 	if portField == "" {
@@ -314,11 +295,41 @@ func FillCell(row string) *domain.CellValues {
 	}
 
 	return &domain.CellValues{
-		Index:   idx,
-		To:      toDisplay,
-		Port:    portField,
-		Action:  actionField,
-		From:    fromDisplay,
-		Comment: commentText,
+		Index:     idx,
+		To:        toDisplay,
+		Port:      portField,
+		Action:    actionField,
+		From:      fromField,
+		Interface: FormatInterfaces(ifaceIn, ifaceOut),
+		Comment:   commentText,
+	}
+}
+
+// FormatInterfaces renders the Interface column, e.g. "in eth0", "out wg0" or "in eth0 out eth1".
+func FormatInterfaces(ifaceIn, ifaceOut string) string {
+	var parts []string
+	if ifaceIn != "" {
+		parts = append(parts, "in", ifaceIn)
+	}
+	if ifaceOut != "" {
+		parts = append(parts, "out", ifaceOut)
+	}
+	if len(parts) == 0 {
+		return "-"
 	}
+	return strings.Join(parts, " ")
+}
+
+// ParseInterfaces is the reverse of FormatInterfaces.
+func ParseInterfaces(input string) (ifaceIn, ifaceOut string) {
+	tokens := strings.Fields(input)
+	for i := 0; i+1 < len(tokens); i += 2 {
+		switch tokens[i] {
+		case "in":
+			ifaceIn = tokens[i+1]
+		case "out":
+			ifaceOut = tokens[i+1]
+		}
+	}
+	return
 }
@@ -131,45 +131,85 @@ func TestFillCell(t *testing.T) {
 		{
 			input: "[1] 192.168.50.10_on_eth1 - ALLOW-FWD 10.0.0.0/8_on_eth0 # No port route",
 			expected: domain.CellValues{
-				Index:   "[1]",
-				To:      "192.168.50.10_on_eth1",
-				Port:    "-",
-				Action:  "ALLOW-FWD",
-				From:    "10.0.0.0/8_on_eth0",
-				Comment: "No port route",
+				Index:     "[1]",
+				To:        "192.168.50.10",
+				Port:      "-",
+				Action:    "ALLOW-FWD",
+				From:      "10.0.0.0/8",
+				Interface: "in eth0 out eth1",
+				Comment:   "No port route",
 			},
 		},
 		{
 			input: "[1] 172.16.0.5/tcp_on_eth2 443 ALLOW-FWD 10.0.0.0/8_on_eth1 # HTTPS route",
 			expected: domain.CellValues{
-				Index:   "[1]",
-				To:      "172.16.0.5/tcp_on_eth2",
-				Port:    "443",
-				Action:  "ALLOW-FWD",
-				From:    "10.0.0.0/8_on_eth1",
-				Comment: "HTTPS route",
+				Index:     "[1]",
+				To:        "172.16.0.5/tcp",
+				Port:      "443",
+				Action:    "ALLOW-FWD",
+				From:      "10.0.0.0/8",
+				Interface: "in eth1 out eth2",
+				Comment:   "HTTPS route",
 			},
 		},
 		{
 			input: "[1] 192.168.0.1/tcp 22 ALLOW-IN Anywhere # SSH rule",
 			expected: domain.CellValues{
-				Index:   "[1]",
-				To:      "192.168.0.1/tcp",
-				Port:    "22",
-				Action:  "ALLOW-IN",
-				From:    "Anywhere",
-				Comment: "SSH rule",
+				Index:     "[1]",
+				To:        "192.168.0.1/tcp",
+				Port:      "22",
+				Action:    "ALLOW-IN",
+				From:      "Anywhere",
+				Interface: "-",
+				Comment:   "SSH rule",
 			},
 		},
 		{
 			input: "[1] 5.5.5.5_on_eth1 - ALLOW-FWD Anywhere_on_eth0",
 			expected: domain.CellValues{
-				Index:   "[1]",
-				To:      "5.5.5.5_on_eth1",
-				Port:    "-",
-				Action:  "ALLOW-FWD",
-				From:    "Anywhere_on_eth0",
-				Comment: "",
+				Index:     "[1]",
+				To:        "5.5.5.5",
+				Port:      "-",
+				Action:    "ALLOW-FWD",
+				From:      "Anywhere",
+				Interface: "in eth0 out eth1",
+				Comment:   "",
+			},
+		},
+		{
+			input: "[1] Anywhere/tcp 22 ALLOW-IN Anywhere_on_eth0",
+			expected: domain.CellValues{
+				Index:     "[1]",
+				To:        "Anywhere/tcp",
+				Port:      "22",
+				Action:    "ALLOW-IN",
+				From:      "Anywhere",
+				Interface: "in eth0",
+				Comment:   "",
+			},
+		},
+		{
+			input: FormatUfwRule("[ 2] 1.1.1.1 53 ALLOW OUT Anywhere on eth0 (out)"),
+			expected: domain.CellValues{
+				Index:     "[2]",
+				To:        "1.1.1.1",
+				Port:      "53",
+				Action:    "ALLOW-OUT",
+				From:      "Anywhere",
+				Interface: "out eth0",
+				Comment:   "",
+			},
+		},
+		{
+			input: FormatUfwRule("[ 3] 80,443/tcp LIMIT IN Anywhere"),
+			expected: domain.CellValues{
+				Index:     "[3]",
+				To:        "Anywhere/tcp",
+				Port:      "80,443",
+				Action:    "LIMIT-IN",
+				From:      "Anywhere",
+				Interface: "-",
+				Comment:   "",
 			},
 		},
 	}
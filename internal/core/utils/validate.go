@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+// maxListedPorts is the number of ports a ufw rule can list, a range counting as two.
+const maxListedPorts = 15
+
+// FieldError is a problem found in a field of a rule form.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// ValidateAddress checks an address of the To and From fields: an IPv4 or
+// IPv6 address, a CIDR, any or an address group. Empty stands for any.
+func ValidateAddress(input string) error {
+	if input == "" || input == "any" || input == "Anywhere" || IsGroup(input) {
+		return nil
+	}
+
+	if address, bits, ok := strings.Cut(input, "/"); ok {
+		addr, err := netip.ParseAddr(address)
+		if err != nil || addr.Zone() != "" {
+			return fmt.Errorf("%q is not an IPv4 or IPv6 address", address)
+		}
+		n, err := strconv.Atoi(bits)
+		if err != nil || n < 0 || n > addr.BitLen() {
+			return fmt.Errorf("/%s is not a prefix length between 0 and %d", bits, addr.BitLen())
+		}
+		return nil
+	}
+
+	addr, err := netip.ParseAddr(input)
+	if err != nil || addr.Zone() != "" {
+		return fmt.Errorf("%q is not an IPv4 or IPv6 address, a CIDR or any", input)
+	}
+	return nil
+}
+
+// ValidatePorts checks a Port field: ports, ranges such as 6000:6007, lists
+// of them, or the names of the services known.
+func ValidatePorts(input string, services *Services) error {
+	if input == "" {
+		return nil
+	}
+
+	count := 0
+	for _, item := range strings.Split(input, ",") {
+		if item == "" {
+			return fmt.Errorf("empty item in the list %q", input)
+		}
+		if _, _, ok := services.Lookup(item); ok {
+			count++
+			continue
+		}
+
+		low, high, isRange := strings.Cut(item, ":")
+		first, err := validatePort(low)
+		if err != nil {
+			return err
+		}
+		count++
+		if !isRange {
+			continue
+		}
+		last, err := validatePort(high)
+		if err != nil {
+			return err
+		}
+		if first > last {
+			return fmt.Errorf("range %s must go from the lowest port to the highest", item)
+		}
+		count++
+	}
+
+	if count > maxListedPorts {
+		return fmt.Errorf("at most %d ports can be listed, a range counting as two", maxListedPorts)
+	}
+	return nil
+}
+
+func validatePort(port string) (int, error) {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither a port nor a known service", port)
+	}
+	if n < 1 || n > 65535 {
+		return 0, fmt.Errorf("port %d is not between 1 and 65535", n)
+	}
+	return n, nil
+}
+
+// ValidateInterface checks an interface name the way the kernel does.
+func ValidateInterface(name string) error {
+	if name == "" {
+		return nil
+	}
+	if len(name) > 15 || name == "." || name == ".." || strings.ContainsAny(name, "/: \t\n") {
+		return fmt.Errorf("%q is not a valid interface name", name)
+	}
+	return nil
+}
+
+// ValidateRule checks every field of a rule, in the order of the form.
+func ValidateRule(fv domain.FormValues, services *Services) []FieldError {
+	var errs []FieldError
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, FieldError{field, err})
+		}
+	}
+
+	check("To", ValidateAddress(fv.To))
+	portErr := ValidatePorts(fv.Port, services)
+	check("Port", portErr)
+	if portErr == nil && fv.Protocol == "" && strings.ContainsAny(fv.Port, ",:") {
+		if _, proto, err := services.Resolve(fv.Port, ""); err == nil && proto == "" {
+			check("Port", fmt.Errorf("lists and ranges of ports need the tcp or udp protocol"))
+		}
+	}
+	check("Interface", ValidateInterface(fv.Interface))
+	check("Interface out", ValidateInterface(fv.InterfaceOut))
+	check("From", ValidateAddress(fv.From))
+
+	if IsAddress(fv.To) && IsAddress(fv.From) && !isAny(fv.To) && !isAny(fv.From) && isV6(fv.To) != isV6(fv.From) {
+		check("From", fmt.Errorf("To and From must both be IPv4 or IPv6 addresses"))
+	}
+	return errs
+}
+
+func isAny(address string) bool {
+	return address == "any" || address == "Anywhere"
+}
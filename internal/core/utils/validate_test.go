@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peltho/tufw/internal/core/domain"
+)
+
+func TestValidateAddress(t *testing.T) {
+	valid := []string{"", "any", "Anywhere", "@office", "10.0.0.1", "10.0.0.0/8", "2001:db8::1", "2001:db8::/32", "0.0.0.0/0"}
+	for _, input := range valid {
+		if err := ValidateAddress(input); err != nil {
+			t.Errorf("%q: unexpected error %v", input, err)
+		}
+	}
+
+	invalid := []string{"10.0.0.300", "10.0.0", "10.0.0.0/33", "2001:db8::/129", "10.0.0.0/", "fe80::1%eth0", "example.com", "10.0.0.1 "}
+	for _, input := range invalid {
+		if err := ValidateAddress(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestValidatePorts(t *testing.T) {
+	services := ParseServices("ssh 22/tcp\nhttp 80/tcp\n")
+
+	valid := []string{"", "22", "65535", "80,443", "6000:6007", "22,6000:6007", "ssh", "http,8080", "1,2,3,4,5,6,7,8,9,10,11,12,13,14,15"}
+	for _, input := range valid {
+		if err := ValidatePorts(input, services); err != nil {
+			t.Errorf("%q: unexpected error %v", input, err)
+		}
+	}
+
+	invalid := []string{"0", "65536", "80,", ",80", "6007:6000", "6000:", "sshd", "22/tcp", "1:2,3:4,5:6,7:8,9:10,11:12,13:14,15:16"}
+	for _, input := range invalid {
+		if err := ValidatePorts(input, services); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+
+	if err := ValidatePorts("ssh", nil); err == nil {
+		t.Errorf("service names cannot be checked without the services")
+	}
+}
+
+func TestValidateInterface(t *testing.T) {
+	for _, name := range []string{"", "eth0", "wg-vpn", "enp0s31f6", "br.100"} {
+		if err := ValidateInterface(name); err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+	for _, name := range []string{"eth0:1", "a/b", "my iface", "..", "averyveryverylongname"} {
+		if err := ValidateInterface(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	services := ParseServices("ssh 22/tcp\ndomain 53/tcp\ndomain 53/udp\n")
+
+	tests := []struct {
+		fv     domain.FormValues
+		fields []string
+	}{
+		{domain.FormValues{To: "10.0.0.1", Port: "ssh", From: "192.168.0.0/16"}, nil},
+		{domain.FormValues{Port: "80,443", Protocol: "tcp"}, nil},
+		{domain.FormValues{Port: "80,443"}, []string{"Port"}},
+		{domain.FormValues{Port: "ssh,2222"}, nil},
+		{domain.FormValues{Port: "domain,5353"}, []string{"Port"}},
+		{domain.FormValues{To: "10.0.0.1", From: "2001:db8::1"}, []string{"From"}},
+		{domain.FormValues{To: "any", From: "2001:db8::1"}, nil},
+		{domain.FormValues{To: "10.0.0.256", Port: "99999", Interface: "eth0:1", From: "nowhere"}, []string{"To", "Port", "Interface", "From"}},
+	}
+
+	for _, tt := range tests {
+		var fields []string
+		for _, err := range ValidateRule(tt.fv, services) {
+			fields = append(fields, err.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("%+v: got errors on %v, want %v", tt.fv, fields, tt.fields)
+		}
+	}
+}